@@ -0,0 +1,208 @@
+package yamlpatch
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// Types that can be tested for with test-type
+const (
+	typeMap    = "map"
+	typeList   = "list"
+	typeString = "string"
+	typeInt    = "int"
+	typeFloat  = "float"
+	typeBool   = "bool"
+	typeNull   = "null"
+)
+
+// lookup returns the node at the operation's path, or nil if the path does
+// not exist
+func lookup(doc Container, op *Operation) *Node {
+	con, key, err := findContainer(doc, &op.Path)
+	if err != nil {
+		return nil
+	}
+
+	val, err := con.Get(key)
+	if err != nil {
+		return nil
+	}
+
+	return val
+}
+
+// lookupPresent returns the node at the operation's path, or an error if the
+// path does not exist
+func lookupPresent(doc Container, op *Operation) (*Node, error) {
+	val := lookup(doc, op)
+	if val == nil {
+		return nil, fmt.Errorf("%s failed: doc is missing path: %s", op.Op, op.Path)
+	}
+
+	return val, nil
+}
+
+func tryTestAbsent(doc Container, op *Operation) error {
+	if lookup(doc, op) != nil {
+		return fmt.Errorf("test-absent failed: path exists: %s", op.Path)
+	}
+
+	return nil
+}
+
+func tryTestPresent(doc Container, op *Operation) error {
+	_, err := lookupPresent(doc, op)
+	return err
+}
+
+func tryTestType(doc Container, op *Operation) error {
+	val, err := lookupPresent(doc, op)
+	if err != nil {
+		return err
+	}
+
+	// an unquoted null type unmarshals as a nil value
+	expected := typeNull
+	if v := op.Value.value(); v != nil {
+		var ok bool
+		expected, ok = v.(string)
+		if !ok {
+			return fmt.Errorf("test-type does not apply: type is not a string: %v", v)
+		}
+	}
+
+	switch expected {
+	case typeMap, typeList, typeString, typeInt, typeFloat, typeBool, typeNull:
+	default:
+		return fmt.Errorf("test-type does not apply: unexpected type: %s", expected)
+	}
+
+	if actual := typeOf(val.Value()); actual != expected {
+		return fmt.Errorf("test-type failed: value at %s is a %s, not a %s", op.Path, actual, expected)
+	}
+
+	return nil
+}
+
+func typeOf(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return typeNull
+	case map[interface{}]interface{}:
+		return typeMap
+	case []interface{}:
+		return typeList
+	case string:
+		return typeString
+	case bool:
+		return typeBool
+	case float64:
+		return typeFloat
+	}
+
+	if _, ok := toInt(v); ok {
+		return typeInt
+	}
+
+	return fmt.Sprintf("%T", v)
+}
+
+func tryTestRegex(doc Container, op *Operation) error {
+	val, err := lookupPresent(doc, op)
+	if err != nil {
+		return err
+	}
+
+	re, err := regexp.Compile(op.Pattern)
+	if err != nil {
+		return fmt.Errorf("test-regex does not apply: %s", err)
+	}
+
+	s, ok := val.Value().(string)
+	if !ok {
+		return fmt.Errorf("test-regex failed: value at %s is not a string: %v", op.Path, val.Value())
+	}
+
+	if !re.MatchString(s) {
+		return fmt.Errorf("test-regex failed: value at %s does not match %s: %s", op.Path, op.Pattern, s)
+	}
+
+	return nil
+}
+
+func tryTestRange(doc Container, op *Operation) error {
+	val, err := lookupPresent(doc, op)
+	if err != nil {
+		return err
+	}
+
+	n, ok := toFloat(val.Value())
+	if !ok {
+		return fmt.Errorf("test-range failed: value at %s is not numeric: %v", op.Path, val.Value())
+	}
+
+	if !inRange(n, op.Min, op.Max) {
+		return fmt.Errorf("test-range failed: value at %s is out of range: %v", op.Path, val.Value())
+	}
+
+	return nil
+}
+
+func tryTestLength(doc Container, op *Operation) error {
+	val, err := lookupPresent(doc, op)
+	if err != nil {
+		return err
+	}
+
+	var length int
+	switch v := val.Value().(type) {
+	case map[interface{}]interface{}:
+		length = len(v)
+	case []interface{}:
+		length = len(v)
+	default:
+		return fmt.Errorf("test-length failed: value at %s is not a map or list: %v", op.Path, v)
+	}
+
+	if op.Value != nil {
+		expected, ok := toInt(op.Value.Value())
+		if !ok {
+			return fmt.Errorf("test-length does not apply: length is not an integer: %v", op.Value.Value())
+		}
+
+		if int64(length) != expected {
+			return fmt.Errorf("test-length failed: value at %s has length %d, not %d", op.Path, length, expected)
+		}
+	}
+
+	if !inRange(float64(length), op.Min, op.Max) {
+		return fmt.Errorf("test-length failed: value at %s has length out of range: %d", op.Path, length)
+	}
+
+	return nil
+}
+
+func inRange(n float64, min, max *float64) bool {
+	return (min == nil || n >= *min) && (max == nil || n <= *max)
+}
+
+func tryTestContains(doc Container, op *Operation) error {
+	val, err := lookupPresent(doc, op)
+	if err != nil {
+		return err
+	}
+
+	ary, ok := val.Container().(*nodeSlice)
+	if !ok {
+		return fmt.Errorf("test-contains failed: value at %s is not a list", op.Path)
+	}
+
+	for _, v := range *ary {
+		if op.Value.Equal(v) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("test-contains failed: list at %s does not contain %v", op.Path, op.Value.value())
+}
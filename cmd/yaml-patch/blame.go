@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	yamlpatch "github.com/krishicks/yaml-patch"
+)
+
+// blameCommand prints the patched document with each changed node annotated
+// with the operation that last changed it
+type blameCommand struct {
+	opts *opts
+}
+
+// Execute implements go-flags' Commander interface
+func (c *blameCommand) Execute(args []string) error {
+	placeholderWrapper := yamlpatch.NewPlaceholderWrapper("{{", "}}")
+	patches := loadPatches(c.opts.OpsFiles, placeholderWrapper)
+	doc := readDoc()
+
+	provenance := yamlpatch.Provenance{}
+	applyOpts := yamlpatch.ApplyOptions{
+		ContinueOnError: c.opts.KeepGoing,
+		Provenance:      provenance,
+	}
+
+	var failed bool
+	mdoc := placeholderWrapper.Wrap(doc)
+	for i, patch := range patches {
+		bs, err := patch.ApplyWithOptions(mdoc, applyOpts)
+		if i > 0 {
+			withoutDocLines(err)
+		}
+
+		if errs, ok := err.(yamlpatch.ApplyErrors); ok {
+			log.Printf("error applying patch %s: %s", c.opts.OpsFiles[i].Path(), errs)
+			failed = true
+		} else if err != nil {
+			log.Fatalf("error applying patch: %s", err)
+		}
+		mdoc = bs
+	}
+
+	relativeSources(provenance)
+
+	bs, err := provenance.Annotate(mdoc)
+	if err != nil {
+		log.Fatalf("error annotating document: %s", err)
+	}
+
+	fmt.Printf("%s", placeholderWrapper.Unwrap(bs))
+
+	if failed {
+		os.Exit(1)
+	}
+
+	return nil
+}
+
+// relativeSources makes the ops file of each source relative to the working
+// directory so that they read as they were given
+func relativeSources(provenance yamlpatch.Provenance) {
+	for path, source := range provenance {
+		source.File = relativePath(source.File)
+		provenance[path] = source
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	yamlpatch "github.com/krishicks/yaml-patch"
+)
+
+// checkIdempotentCommand reports the operations in the ops files that change
+// the document again when the ops files are applied a second time
+type checkIdempotentCommand struct {
+	opts *opts
+}
+
+// Execute implements go-flags' Commander interface
+func (c *checkIdempotentCommand) Execute(args []string) error {
+	placeholderWrapper := yamlpatch.NewPlaceholderWrapper("{{", "}}")
+
+	var patch yamlpatch.Patch
+	for _, p := range loadPatches(c.opts.OpsFiles, placeholderWrapper) {
+		patch = append(patch, p...)
+	}
+
+	for i := range patch {
+		patch[i].Source.File = relativePath(patch[i].Source.File)
+	}
+
+	doc := readDoc()
+
+	idempotent, issues, err := patch.IsIdempotent(placeholderWrapper.Wrap(doc))
+	if err != nil {
+		log.Fatalf("error applying patch: %s", err)
+	}
+
+	for _, issue := range issues {
+		fmt.Println(issue)
+	}
+
+	if !idempotent {
+		os.Exit(1)
+	}
+
+	return nil
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+
+	yamlpatch "github.com/krishicks/yaml-patch"
+)
+
+// conflictsCommand reports the conflicts between ops files applied to the
+// same document
+type conflictsCommand struct {
+	opts *opts
+}
+
+// Execute implements go-flags' Commander interface
+func (c *conflictsCommand) Execute(args []string) error {
+	placeholderWrapper := yamlpatch.NewPlaceholderWrapper("{{", "}}")
+	patches := loadPatches(c.opts.OpsFiles, placeholderWrapper)
+
+	var names []string
+	for _, opsFile := range c.opts.OpsFiles {
+		names = append(names, relativePath(opsFile.Path()))
+	}
+
+	for _, patch := range patches {
+		for i := range patch {
+			patch[i].Source.File = relativePath(patch[i].Source.File)
+		}
+	}
+
+	var doc []byte
+	switch len(args) {
+	case 0:
+		doc = readDoc()
+	case 1:
+		var err error
+		doc, err = ioutil.ReadFile(args[0])
+		if err != nil {
+			log.Fatalf("error reading document: %s", err)
+		}
+	default:
+		log.Fatalf("error: expected at most one document, got %d", len(args))
+	}
+
+	conflicts, err := yamlpatch.Conflicts(placeholderWrapper.Wrap(doc), patches...)
+	if err != nil {
+		log.Fatalf("error finding conflicts: %s", err)
+	}
+
+	for _, conflict := range conflicts {
+		a, b := names[conflict.Patches[0]], names[conflict.Patches[1]]
+
+		switch {
+		case conflict.Ops[0] != nil:
+			fmt.Println(conflict)
+		case conflict.Err != nil:
+			fmt.Printf("%s: applying %s before %s fails: %s\n", conflict.Kind, a, b, conflict.Err)
+		default:
+			fmt.Printf("%s at %s: the result differs depending on whether %s or %s is applied first\n", conflict.Kind, conflict.Path, a, b)
+		}
+	}
+
+	if len(conflicts) > 0 {
+		os.Exit(1)
+	}
+
+	return nil
+}
@@ -5,40 +5,205 @@ import (
 	"io/ioutil"
 	"log"
 	"os"
+	"path/filepath"
+	"strings"
 
 	flags "github.com/jessevdk/go-flags"
 	yamlpatch "github.com/krishicks/yaml-patch"
 )
 
 type opts struct {
-	OpsFiles []FileFlag `long:"ops-file" short:"o" value-name:"PATH" description:"Path to file with one or more operations"`
+	OpsFiles    []FileFlag `long:"ops-file" short:"o" value-name:"PATH" description:"Path to file with one or more operations"`
+	KeepGoing   bool       `long:"keep-going" short:"k" description:"Skip operations that fail and report every failure"`
+	Trace       bool       `long:"trace" description:"Print what each operation did to stderr"`
+	TraceFormat string     `long:"trace-format" default:"text" choice:"text" choice:"json" description:"Format of the trace"`
+	DryRun      bool       `long:"dry-run" description:"Print a diff of the changes instead of the result, exiting non-zero if there are changes"`
+	Color       bool       `long:"color" description:"Colorize the diff printed by --dry-run"`
+	Annotate    bool       `long:"annotate" description:"Comment each node written by an operation with the operation's ops file and comment"`
 }
 
 func main() {
 	var o opts
-	_, err := flags.Parse(&o)
+	parser := flags.NewParser(&o, flags.Default)
+	parser.SubcommandsOptional = true
+
+	_, err := parser.AddCommand(
+		"blame",
+		"Print the patched document annotated with the operation that changed each node",
+		"Applies the ops files like the default command, then prints the result with a comment on each changed node naming the ops file and line of the operation that last changed it.",
+		&blameCommand{opts: &o},
+	)
+	if err != nil {
+		log.Fatalf("error: %s\n", err)
+	}
+
+	_, err = parser.AddCommand(
+		"squash",
+		"Print a single ops file equivalent to the given ops files",
+		"Composes the ops files in order and merges sequential operations on the same paths, printing the resulting ops file.",
+		&squashCommand{opts: &o},
+	)
+	if err != nil {
+		log.Fatalf("error: %s\n", err)
+	}
+
+	_, err = parser.AddCommand(
+		"resolve",
+		"Print the ops files as a patch with only RFC 6902 operations and plain pointers",
+		"Applies the ops files to the document read from stdin, expanding each path that uses extended syntax into the paths it matches and each extended operation into plain ones, and prints the resulting patch.",
+		&resolveCommand{opts: &o},
+	)
+	if err != nil {
+		log.Fatalf("error: %s\n", err)
+	}
+
+	_, err = parser.AddCommand(
+		"conflicts",
+		"Report conflicts between ops files applied to the same document",
+		"Applies each ops file to the document, given as an argument or read from stdin, independently, and reports writes to the same paths, removals of paths that another ops file modifies, and results that depend on the order of the ops files. Exits non-zero if there are conflicts.",
+		&conflictsCommand{opts: &o},
+	)
+	if err != nil {
+		log.Fatalf("error: %s\n", err)
+	}
+
+	_, err = parser.AddCommand(
+		"merge3",
+		"Merge the changes made to a base document by two others",
+		"Takes the changes that ours and theirs each made to base, printing the result. Paths changed differently by each side are reported as conflicts and our side is kept, unless a strategy is given or --markers writes both sides between conflict markers. Exits non-zero if there are conflicts not decided by a strategy.",
+		&merge3Command{},
+	)
+	if err != nil {
+		log.Fatalf("error: %s\n", err)
+	}
+
+	_, err = parser.AddCommand(
+		"check-idempotent",
+		"Report operations that change the document again when the ops files are reapplied",
+		"Applies the ops files to the document read from stdin, then applies them again to the result, and reports each operation that changed it again or failed the second time, with an idempotent alternative where one is known. Exits non-zero if the ops files are not idempotent.",
+		&checkIdempotentCommand{opts: &o},
+	)
+	if err != nil {
+		log.Fatalf("error: %s\n", err)
+	}
+
+	_, err = parser.Parse()
 
 	if err != nil {
 		if flagsErr, ok := err.(*flags.Error); ok && flagsErr.Type == flags.ErrHelp {
 			os.Exit(0)
 		} else {
-			log.Fatalf("error: %s\n", err)
 			os.Exit(1)
 		}
 	}
 
+	if parser.Active != nil {
+		return
+	}
+
 	placeholderWrapper := yamlpatch.NewPlaceholderWrapper("{{", "}}")
+	patches := loadPatches(o.OpsFiles, placeholderWrapper)
+	doc := readDoc()
 
-	var patches []yamlpatch.Patch
+	applyOpts := yamlpatch.ApplyOptions{ContinueOnError: o.KeepGoing}
+
+	var names []string
 	for _, opsFile := range o.OpsFiles {
+		names = append(names, opsFile.Path())
+	}
+
+	if o.Annotate && len(patches) > 1 {
+		// comments do not survive the document being decoded again, so the
+		// patches are applied as one
+		var all yamlpatch.Patch
+		for _, patch := range patches {
+			all = append(all, patch...)
+		}
+
+		patches = []yamlpatch.Patch{all}
+		names = []string{strings.Join(names, ", ")}
+	}
+
+	if o.Annotate {
+		applyOpts.Annotate = true
+		for _, patch := range patches {
+			for i := range patch {
+				patch[i].Source.File = relativePath(patch[i].Source.File)
+			}
+		}
+	}
+
+	var failed bool
+	mdoc := placeholderWrapper.Wrap(doc)
+
+	original, err := yamlpatch.Patch(nil).Apply(mdoc)
+	if err != nil {
+		log.Fatalf("error reading document: %s", err)
+	}
+	for i, patch := range patches {
 		var bs []byte
-		bs, err = ioutil.ReadFile(opsFile.Path())
-		if err != nil {
-			log.Fatalf("error reading opsfile: %s", err)
+		if o.Trace {
+			var trace yamlpatch.Trace
+			bs, trace, err = patch.ApplyWithTrace(mdoc, applyOpts)
+			writeTrace(names[i], trace, o.TraceFormat)
+		} else {
+			bs, err = patch.ApplyWithOptions(mdoc, applyOpts)
 		}
 
-		var patch yamlpatch.Patch
-		patch, err = yamlpatch.DecodePatch(placeholderWrapper.Wrap(bs))
+		if i > 0 {
+			withoutDocLines(err)
+		}
+
+		if errs, ok := err.(yamlpatch.ApplyErrors); ok {
+			log.Printf("error applying patch %s: %s", names[i], errs)
+			failed = true
+		} else if err != nil {
+			log.Fatalf("error applying patch: %s", err)
+		}
+		mdoc = bs
+	}
+
+	if o.DryRun {
+		diff := yamlpatch.UnifiedDiff(placeholderWrapper.Unwrap(original), placeholderWrapper.Unwrap(mdoc), yamlpatch.DiffOptions{
+			FromFile: "original",
+			ToFile:   "patched",
+			Color:    o.Color,
+		})
+		fmt.Print(diff)
+
+		if diff != "" {
+			failed = true
+		}
+	} else {
+		fmt.Printf("%s", placeholderWrapper.Unwrap(mdoc))
+	}
+
+	if failed {
+		os.Exit(1)
+	}
+}
+
+func writeTrace(opsFile string, trace yamlpatch.Trace, format string) {
+	var err error
+	if format == "json" {
+		err = trace.WriteJSON(os.Stderr)
+	} else {
+		fmt.Fprintf(os.Stderr, "# %s\n", opsFile)
+		err = trace.WriteText(os.Stderr)
+	}
+
+	if err != nil {
+		log.Fatalf("error writing trace: %s", err)
+	}
+}
+
+// loadPatches decodes each ops file, wrapping any placeholders
+func loadPatches(opsFiles []FileFlag, wrapper *yamlpatch.PlaceholderWrapper) []yamlpatch.Patch {
+	loader := placeholderLoader{wrapper: wrapper}
+
+	var patches []yamlpatch.Patch
+	for _, opsFile := range opsFiles {
+		patch, err := yamlpatch.DecodePatchFile(loader, opsFile.Path())
 		if err != nil {
 			log.Fatalf("error decoding opsfile: %s", err)
 		}
@@ -46,18 +211,69 @@ func main() {
 		patches = append(patches, patch)
 	}
 
+	return patches
+}
+
+// relativePath returns the path relative to the working directory where
+// possible, so that it reads as it was given
+func relativePath(path string) string {
+	wd, err := os.Getwd()
+	if err != nil {
+		return path
+	}
+
+	rel, err := filepath.Rel(wd, path)
+	if err != nil {
+		return path
+	}
+
+	return rel
+}
+
+// withoutDocLines clears the document lines of the failed operations in err.
+// Every ops file after the first is applied to the output of the one before
+// it, whose lines are not those of the document the user passed.
+func withoutDocLines(err error) {
+	var errs yamlpatch.ApplyErrors
+	switch e := err.(type) {
+	case *yamlpatch.ApplyError:
+		errs = yamlpatch.ApplyErrors{e}
+	case yamlpatch.ApplyErrors:
+		errs = e
+	}
+
+	for _, e := range errs {
+		e.DocLine = 0
+	}
+}
+
+func readDoc() []byte {
 	doc, err := ioutil.ReadAll(os.Stdin)
 	if err != nil {
 		log.Fatalf("error reading from stdin: %s", err)
 	}
 
-	mdoc := placeholderWrapper.Wrap(doc)
-	for _, patch := range patches {
-		mdoc, err = patch.Apply(mdoc)
-		if err != nil {
-			log.Fatalf("error applying patch: %s", err)
-		}
+	return doc
+}
+
+// placeholderLoader reads ops files from disk, wrapping any placeholders so
+// that they are valid YAML
+type placeholderLoader struct {
+	yamlpatch.OSLoader
+	wrapper *yamlpatch.PlaceholderWrapper
+}
+
+func (l placeholderLoader) ReadFile(path string) ([]byte, error) {
+	bs, err := l.OSLoader.ReadFile(path)
+	if err != nil {
+		return nil, err
 	}
 
-	fmt.Printf("%s", placeholderWrapper.Unwrap(mdoc))
+	return l.wrapper.Wrap(bs), nil
+}
+
+// ReadValueFile reads the file a value is taken from as it is, since raw and
+// encoded values use its contents verbatim
+func (l placeholderLoader) ReadValueFile(path string) ([]byte, error) {
+	return l.OSLoader.ReadFile(path)
 }
@@ -1,6 +1,12 @@
 package main_test
 
 import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	"github.com/onsi/gomega/gexec"
@@ -11,4 +17,41 @@ var _ = Describe("yaml-patch", func() {
 		_, err := gexec.Build("github.com/krishicks/yaml-patch/cmd/yaml-patch")
 		Expect(err).NotTo(HaveOccurred())
 	})
+
+	It("wraps placeholders in ops files but not in the files values are taken from", func() {
+		bin, err := gexec.Build("github.com/krishicks/yaml-patch/cmd/yaml-patch")
+		Expect(err).NotTo(HaveOccurred())
+
+		dir, err := ioutil.TempDir("", "yaml-patch")
+		Expect(err).NotTo(HaveOccurred())
+		defer os.RemoveAll(dir)
+
+		Expect(ioutil.WriteFile(filepath.Join(dir, "template.txt"), []byte("{{ x }}"), 0644)).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(dir, "ops.yml"), []byte(`---
+- op: add
+  path: /name
+  value: {{ name }}
+- op: add
+  path: /raw
+  valueFile: template.txt
+  raw: true
+- op: add
+  path: /encoded
+  valueFile: template.txt
+  encoding: base64
+`), 0644)).To(Succeed())
+
+		cmd := exec.Command(bin, "-o", filepath.Join(dir, "ops.yml"))
+		cmd.Stdin = strings.NewReader("foo: bar\n")
+
+		session, err := gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(session).Should(gexec.Exit(0))
+
+		Expect(string(session.Out.Contents())).To(Equal(`encoded: e3sgeCB9fQ==
+foo: bar
+name: {{ name }}
+raw: {{ x }}
+`))
+	})
 })
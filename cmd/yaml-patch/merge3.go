@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+
+	yamlpatch "github.com/krishicks/yaml-patch"
+)
+
+// merge3Command merges the changes made to a base document by two others
+type merge3Command struct {
+	Strategy string `long:"strategy" choice:"ours" choice:"theirs" description:"Decide each conflict in favor of one side"`
+	Markers  bool   `long:"markers" description:"Write both sides of each conflict between conflict markers"`
+
+	Args struct {
+		Base   FileFlag `positional-arg-name:"BASE" description:"Path to the common ancestor"`
+		Ours   FileFlag `positional-arg-name:"OURS" description:"Path to our changed document"`
+		Theirs FileFlag `positional-arg-name:"THEIRS" description:"Path to their changed document"`
+	} `positional-args:"yes" required:"yes"`
+}
+
+// Execute implements go-flags' Commander interface
+func (c *merge3Command) Execute(args []string) error {
+	placeholderWrapper := yamlpatch.NewPlaceholderWrapper("{{", "}}")
+
+	var docs [][]byte
+	for _, path := range []FileFlag{c.Args.Base, c.Args.Ours, c.Args.Theirs} {
+		doc, err := ioutil.ReadFile(path.Path())
+		if err != nil {
+			log.Fatalf("error reading document: %s", err)
+		}
+
+		docs = append(docs, placeholderWrapper.Wrap(doc))
+	}
+
+	merged, conflicts, err := yamlpatch.Merge3WithOptions(docs[0], docs[1], docs[2], yamlpatch.Merge3Options{
+		Strategy: yamlpatch.Merge3Strategy(c.Strategy),
+		Markers:  c.Markers,
+	})
+	if err != nil {
+		log.Fatalf("error merging documents: %s", err)
+	}
+
+	for _, conflict := range conflicts {
+		log.Printf("%s", conflict)
+	}
+
+	fmt.Printf("%s", placeholderWrapper.Unwrap(merged))
+
+	if len(conflicts) > 0 && c.Strategy == "" {
+		os.Exit(1)
+	}
+
+	return nil
+}
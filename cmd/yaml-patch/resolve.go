@@ -0,0 +1,56 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+
+	yamlpatch "github.com/krishicks/yaml-patch"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// resolveCommand prints the ops files resolved against the document into a
+// patch with only RFC 6902 operations and plain pointers
+type resolveCommand struct {
+	opts   *opts
+	Format string `long:"format" default:"yaml" choice:"yaml" choice:"json" description:"Format of the resolved patch"`
+}
+
+// Execute implements go-flags' Commander interface
+func (c *resolveCommand) Execute(args []string) error {
+	placeholderWrapper := yamlpatch.NewPlaceholderWrapper("{{", "}}")
+	patches := loadPatches(c.opts.OpsFiles, placeholderWrapper)
+	doc := placeholderWrapper.Wrap(readDoc())
+
+	var resolved yamlpatch.Patch
+	for i, patch := range patches {
+		rpatch, err := patch.Resolve(doc)
+		if err != nil {
+			log.Fatalf("error resolving patch %s: %s", c.opts.OpsFiles[i].Path(), err)
+		}
+
+		doc, err = rpatch.Apply(doc)
+		if err != nil {
+			log.Fatalf("error applying patch %s: %s", c.opts.OpsFiles[i].Path(), err)
+		}
+
+		resolved = append(resolved, rpatch...)
+	}
+
+	var bs []byte
+	var err error
+	if c.Format == "json" {
+		bs, err = json.MarshalIndent(resolved, "", "  ")
+		bs = append(bs, '\n')
+	} else {
+		bs, err = yaml.Marshal(resolved)
+	}
+
+	if err != nil {
+		log.Fatalf("error encoding patch: %s", err)
+	}
+
+	fmt.Printf("%s", placeholderWrapper.Unwrap(bs))
+
+	return nil
+}
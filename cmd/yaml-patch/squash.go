@@ -0,0 +1,29 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	yamlpatch "github.com/krishicks/yaml-patch"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// squashCommand prints a single ops file equivalent to the given ops files
+type squashCommand struct {
+	opts *opts
+}
+
+// Execute implements go-flags' Commander interface
+func (c *squashCommand) Execute(args []string) error {
+	placeholderWrapper := yamlpatch.NewPlaceholderWrapper("{{", "}}")
+	patches := loadPatches(c.opts.OpsFiles, placeholderWrapper)
+
+	bs, err := yaml.Marshal(yamlpatch.Compose(patches...))
+	if err != nil {
+		log.Fatalf("error encoding patch: %s", err)
+	}
+
+	fmt.Printf("%s", placeholderWrapper.Unwrap(bs))
+
+	return nil
+}
@@ -0,0 +1,269 @@
+package yamlpatch
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// ConflictKind is the way two patches conflict
+type ConflictKind string
+
+// Conflict kinds
+const (
+	// ConflictOverlap is when both patches write different values to the
+	// same path, or one writes beneath a path the other writes
+	ConflictOverlap ConflictKind = "overlapping writes"
+	// ConflictRemoveModify is when one patch removes a path that the other
+	// writes to or beneath
+	ConflictRemoveModify ConflictKind = "remove-modify"
+	// ConflictOrder is when the result differs depending on which patch is
+	// applied first, other than because of an overlap or removal
+	ConflictOrder ConflictKind = "order-dependent"
+)
+
+// Conflict is a conflict between two patches applied to the same document
+type Conflict struct {
+	Kind ConflictKind
+	// Patches are the indexes of the two patches
+	Patches [2]int
+	// Path is where the patches conflict
+	Path OpPath
+	// Ops are the conflicting operations of each patch, resolved against the
+	// document. They are nil for an ordering conflict.
+	Ops [2]*Operation
+	// Err is set when the patches apply in one order but not the other
+	Err error
+}
+
+func (c Conflict) String() string {
+	switch {
+	case c.Ops[0] != nil && c.Ops[1] != nil:
+		return fmt.Sprintf("%s at %s: %s %s (%s) and %s %s (%s)", c.Kind, c.Path,
+			c.Ops[0].Op, c.Ops[0].Path, c.Ops[0].Source, c.Ops[1].Op, c.Ops[1].Path, c.Ops[1].Source)
+	case c.Err != nil:
+		return fmt.Sprintf("%s: applying patch %d before patch %d fails: %s", c.Kind, c.Patches[0], c.Patches[1], c.Err)
+	}
+
+	return fmt.Sprintf("%s at %s: patches %d and %d give different results", c.Kind, c.Path, c.Patches[0], c.Patches[1])
+}
+
+// Conflicts returns the conflicts between each pair of patches when they are
+// applied to the document independently: writes of different values to the
+// same path or to paths beneath one another, removals of paths that the
+// other patch writes, and any other differences in the result depending on
+// the order the patches are applied in.
+func Conflicts(doc []byte, patches ...Patch) ([]Conflict, error) {
+	resolved := make([]Patch, len(patches))
+	for i, p := range patches {
+		var err error
+		resolved[i], err = p.Resolve(doc)
+		if err != nil {
+			return nil, fmt.Errorf("patch %d: %s", i, err)
+		}
+	}
+
+	var conflicts []Conflict
+	for i := range patches {
+		for j := i + 1; j < len(patches); j++ {
+			pair := writeConflicts(i, j, resolved[i], resolved[j])
+
+			order, err := orderConflicts(doc, i, j, patches[i], patches[j], pair)
+			if err != nil {
+				return nil, err
+			}
+
+			conflicts = append(conflicts, pair...)
+			conflicts = append(conflicts, order...)
+		}
+	}
+
+	return conflicts, nil
+}
+
+// writeConflicts returns the conflicts between the writes of two resolved
+// patches
+func writeConflicts(i, j int, a, b Patch) []Conflict {
+	var conflicts []Conflict
+
+	for ai := range a {
+		for bi := range b {
+			aop, bop := &a[ai], &b[bi]
+
+			for _, ap := range writtenPaths(aop) {
+				for _, bp := range writtenPaths(bop) {
+					if kind, ok := conflictKind(aop, ap, bop, bp); ok {
+						path := ap
+						if len(bp) < len(ap) {
+							path = bp
+						}
+
+						conflicts = append(conflicts, Conflict{
+							Kind:    kind,
+							Patches: [2]int{i, j},
+							Path:    path,
+							Ops:     [2]*Operation{aop, bop},
+						})
+					}
+				}
+			}
+		}
+	}
+
+	return conflicts
+}
+
+// writtenPaths returns the paths written by a resolved operation
+func writtenPaths(op *Operation) []OpPath {
+	switch op.Op {
+	case opMove:
+		return []OpPath{op.From, op.Path}
+	case opAdd, opReplace, opRemove, opCopy:
+		return []OpPath{op.Path}
+	}
+
+	return nil
+}
+
+// conflictKind returns how writes to the two paths conflict, if they do
+func conflictKind(a *Operation, ap OpPath, b *Operation, bp OpPath) (ConflictKind, bool) {
+	if relatePaths(ap, bp) != pathsOverlap {
+		return "", false
+	}
+
+	// appends to the same array only conflict in the order of the elements,
+	// which orderConflicts reports
+	if ap == bp && strings.HasSuffix(string(ap), "/-") {
+		return "", false
+	}
+
+	aRemoves := removes(a, ap)
+	bRemoves := removes(b, bp)
+
+	switch {
+	case aRemoves && bRemoves:
+		return ConflictOverlap, true
+	case aRemoves || bRemoves:
+		return ConflictRemoveModify, true
+	case ap == bp && a.Op != opMove && b.Op != opMove && a.Op != opCopy && b.Op != opCopy && a.Value.Equal(b.Value):
+		return "", false
+	}
+
+	return ConflictOverlap, true
+}
+
+// removes returns whether the operation removes the path
+func removes(op *Operation, path OpPath) bool {
+	return op.Op == opRemove || (op.Op == opMove && path == op.From)
+}
+
+// orderConflicts applies the patches in each order, returning a conflict for
+// each path whose result differs that is not already covered by one of the
+// given conflicts, or for an order in which the patches fail to apply when
+// none of the given conflicts explain why
+func orderConflicts(doc []byte, i, j int, a, b Patch, known []Conflict) ([]Conflict, error) {
+	ab, abErr := append(append(Patch{}, a...), b...).Apply(doc)
+	ba, baErr := append(append(Patch{}, b...), a...).Apply(doc)
+
+	switch {
+	case (abErr != nil || baErr != nil) && len(known) > 0:
+		return nil, nil
+	case abErr != nil:
+		return []Conflict{{Kind: ConflictOrder, Patches: [2]int{i, j}, Err: abErr}}, nil
+	case baErr != nil:
+		return []Conflict{{Kind: ConflictOrder, Patches: [2]int{j, i}, Err: baErr}}, nil
+	}
+
+	var abVal, baVal interface{}
+	err := yaml.Unmarshal(ab, &abVal)
+	if err != nil {
+		return nil, err
+	}
+
+	err = yaml.Unmarshal(ba, &baVal)
+	if err != nil {
+		return nil, err
+	}
+
+	var conflicts []Conflict
+
+paths:
+	for _, path := range diffPaths(abVal, baVal, "") {
+		for _, c := range known {
+			if relatePaths(c.Path, path) == pathsOverlap {
+				continue paths
+			}
+		}
+
+		conflicts = append(conflicts, Conflict{Kind: ConflictOrder, Patches: [2]int{i, j}, Path: path})
+	}
+
+	return conflicts, nil
+}
+
+// diffPaths returns the outermost paths at which the two values differ
+func diffPaths(a, b interface{}, path OpPath) []OpPath {
+	switch at := a.(type) {
+	case map[interface{}]interface{}:
+		bt, ok := b.(map[interface{}]interface{})
+		if !ok {
+			break
+		}
+
+		keys := map[string]interface{}{}
+		for k := range at {
+			keys[fmt.Sprint(k)] = k
+		}
+		for k := range bt {
+			keys[fmt.Sprint(k)] = k
+		}
+
+		var names []string
+		for name := range keys {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		var paths []OpPath
+		for _, name := range names {
+			k := keys[name]
+			av, aok := at[k]
+			bv, bok := bt[k]
+
+			child := OpPath(fmt.Sprintf("%s/%s", path, encodePatchKey(name)))
+			if aok != bok {
+				paths = append(paths, child)
+				continue
+			}
+
+			paths = append(paths, diffPaths(av, bv, child)...)
+		}
+
+		return paths
+	case []interface{}:
+		bt, ok := b.([]interface{})
+		if !ok || len(at) != len(bt) {
+			break
+		}
+
+		var paths []OpPath
+		for i := range at {
+			paths = append(paths, diffPaths(at[i], bt[i], OpPath(fmt.Sprintf("%s/%d", path, i)))...)
+		}
+
+		return paths
+	}
+
+	if reflect.DeepEqual(a, b) {
+		return nil
+	}
+
+	if path == "" {
+		path = "/"
+	}
+
+	return []OpPath{path}
+}
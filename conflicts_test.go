@@ -0,0 +1,173 @@
+package yamlpatch_test
+
+import (
+	yamlpatch "github.com/krishicks/yaml-patch"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Conflicts", func() {
+	doc := []byte(`---
+instances: 1
+labels:
+  team: core
+jobs:
+- name: job1
+  plan:
+  - get: A
+- name: job2
+  plan: []
+`)
+
+	conflicts := func(patches ...string) []yamlpatch.Conflict {
+		var ps []yamlpatch.Patch
+		for _, p := range patches {
+			ps = append(ps, decodePatch(p))
+		}
+
+		cs, err := yamlpatch.Conflicts(doc, ps...)
+		Expect(err).NotTo(HaveOccurred())
+		return cs
+	}
+
+	It("reports nothing for patches that write different paths", func() {
+		Expect(conflicts(`---
+- op: replace
+  path: /instances
+  value: 3
+`, `---
+- op: add
+  path: /labels/tier
+  value: web
+`)).To(BeEmpty())
+	})
+
+	It("reports nothing for patches that write the same value to a path", func() {
+		Expect(conflicts(`---
+- op: replace
+  path: /instances
+  value: 3
+`, `---
+- op: replace
+  path: /instances
+  value: 3
+`)).To(BeEmpty())
+	})
+
+	It("reports writes of different values to the same path", func() {
+		cs := conflicts(`---
+- op: replace
+  path: /instances
+  value: 3
+`, `---
+- op: test
+  path: /instances
+  value: 1
+- op: replace
+  path: /instances
+  value: 5
+`)
+
+		Expect(cs).To(HaveLen(1))
+		Expect(cs[0].Kind).To(Equal(yamlpatch.ConflictOverlap))
+		Expect(cs[0].Patches).To(Equal([2]int{0, 1}))
+		Expect(cs[0].Path).To(Equal(yamlpatch.OpPath("/instances")))
+		Expect(cs[0].String()).To(Equal("overlapping writes at /instances: replace /instances (line 2) and replace /instances (line 5)"))
+	})
+
+	It("reports writes beneath a path that the other patch writes, after expanding extended syntax", func() {
+		cs := conflicts(`---
+- op: replace
+  path: /jobs/name=job1/plan
+  value: []
+`, `---
+- op: add
+  path: /jobs/get=A/trigger
+  value: true
+`)
+
+		Expect(cs).To(HaveLen(1))
+		Expect(cs[0].Kind).To(Equal(yamlpatch.ConflictOverlap))
+		Expect(cs[0].Path).To(Equal(yamlpatch.OpPath("/jobs/0/plan")))
+		Expect(cs[0].Ops[1].Path).To(Equal(yamlpatch.OpPath("/jobs/0/plan/0/trigger")))
+	})
+
+	It("reports removals of paths that the other patch modifies", func() {
+		cs := conflicts(`---
+- op: remove
+  path: /labels
+`, `---
+- op: add
+  path: /labels/tier
+  value: web
+`, `---
+- op: replace
+  path: /instances
+  value: 2
+`)
+
+		Expect(cs).To(HaveLen(1))
+		Expect(cs[0].Kind).To(Equal(yamlpatch.ConflictRemoveModify))
+		Expect(cs[0].Patches).To(Equal([2]int{0, 1}))
+		Expect(cs[0].Path).To(Equal(yamlpatch.OpPath("/labels")))
+	})
+
+	It("reports moves of paths that the other patch modifies", func() {
+		cs := conflicts(`---
+- op: add
+  path: /jobs/name=job2/plan/-
+  value: {get: B}
+`, `---
+- op: rename
+  path: /jobs/name=job2/plan
+  to: steps
+`)
+
+		Expect(cs).To(HaveLen(1))
+		Expect(cs[0].Kind).To(Equal(yamlpatch.ConflictRemoveModify))
+		Expect(cs[0].String()).To(Equal("remove-modify at /jobs/1/plan: add /jobs/1/plan/- (line 2) and move /jobs/1/steps (line 2)"))
+	})
+
+	It("reports results that depend on the order the patches are applied in", func() {
+		cs := conflicts(`---
+- op: add
+  path: /jobs/-
+  value: {name: job3}
+`, `---
+- op: add
+  path: /jobs/-
+  value: {name: job4}
+`)
+
+		Expect(cs).To(HaveLen(2))
+		Expect(cs[0].Kind).To(Equal(yamlpatch.ConflictOrder))
+		Expect(cs[0].Path).To(Equal(yamlpatch.OpPath("/jobs/2/name")))
+		Expect(cs[1].Path).To(Equal(yamlpatch.OpPath("/jobs/3/name")))
+	})
+
+	It("reports patches that fail when applied in one order", func() {
+		cs := conflicts(`---
+- op: add
+  path: /jobs/0
+  value: {name: job0, plan: []}
+`, `---
+- op: test
+  path: /jobs/1/name
+  value: job2
+`)
+
+		Expect(cs).To(HaveLen(1))
+		Expect(cs[0].Kind).To(Equal(yamlpatch.ConflictOrder))
+		Expect(cs[0].Patches).To(Equal([2]int{0, 1}))
+		Expect(cs[0].String()).To(HavePrefix("order-dependent: applying patch 0 before patch 1 fails: operation 1 (test /jobs/1/name)"))
+	})
+
+	It("returns an error when a patch does not apply to the document", func() {
+		_, err := yamlpatch.Conflicts(doc, decodePatch(`---
+- op: remove
+  path: /missing
+`))
+		Expect(err).To(MatchError(HavePrefix("patch 0: operation 0 (remove /missing)")))
+	})
+})
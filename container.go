@@ -2,6 +2,8 @@ package yamlpatch
 
 import (
 	"fmt"
+	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 )
@@ -14,6 +16,12 @@ type Container interface {
 	Remove(key string) error
 }
 
+// Renamer is implemented by Containers that can rename a key in place. An
+// order-preserving Container should keep the renamed key at its position.
+type Renamer interface {
+	Rename(from, to string, overwrite bool) error
+}
+
 type nodeMap map[interface{}]*Node
 
 func (n *nodeMap) Set(key string, val *Node) error {
@@ -40,6 +48,25 @@ func (n *nodeMap) Remove(key string) error {
 	return nil
 }
 
+func (n *nodeMap) Rename(from, to string, overwrite bool) error {
+	val, ok := (*n)[from]
+	if !ok {
+		return fmt.Errorf("Unable to rename nonexistent key: %s", from)
+	}
+
+	if from == to {
+		return nil
+	}
+
+	if _, ok := (*n)[to]; ok && !overwrite {
+		return fmt.Errorf("Unable to rename key %s to existing key: %s", from, to)
+	}
+
+	delete(*n, from)
+	(*n)[to] = val
+	return nil
+}
+
 type nodeSlice []*Node
 
 func (n *nodeSlice) Set(index string, val *Node) error {
@@ -127,6 +154,94 @@ func (n *nodeSlice) Remove(index string) error {
 
 }
 
+// Extend appends each of the given values
+func (n *nodeSlice) Extend(vals ...*Node) {
+	*n = append(*n, vals...)
+}
+
+// AddUnique appends the value unless an equal value is already present, and
+// returns whether it was appended
+func (n *nodeSlice) AddUnique(val *Node) bool {
+	for _, v := range *n {
+		if val.Equal(v) {
+			return false
+		}
+	}
+
+	*n = append(*n, val)
+	return true
+}
+
+// RemoveValue removes every element equal to the value, and returns the
+// number of elements removed
+func (n *nodeSlice) RemoveValue(val *Node) int {
+	ary := make([]*Node, 0, len(*n))
+
+	for _, v := range *n {
+		if !val.Equal(v) {
+			ary = append(ary, v)
+		}
+	}
+
+	removed := len(*n) - len(ary)
+	*n = ary
+	return removed
+}
+
+// Sort stably sorts the elements by their value, or by the value found at
+// key within each element when key is not empty
+func (n *nodeSlice) Sort(key string, descending bool) {
+	ary := *n
+
+	sortValue := func(i int) interface{} {
+		if key == "" {
+			return ary[i].value()
+		}
+		return keyValue(ary[i], key)
+	}
+
+	sort.SliceStable(ary, func(i, j int) bool {
+		cmp := compareValues(sortValue(i), sortValue(j))
+		if descending {
+			return cmp > 0
+		}
+		return cmp < 0
+	})
+}
+
+// Dedupe removes every element that is equal to an earlier element, or that
+// has the same value at key as an earlier element when key is not empty. When
+// deduping by key, elements without the key are kept.
+func (n *nodeSlice) Dedupe(key string) {
+	ary := make([]*Node, 0, len(*n))
+
+	var seen []interface{}
+	for _, v := range *n {
+		var val interface{}
+		if key == "" {
+			val = v.value()
+		} else if val = keyValue(v, key); val == nil {
+			ary = append(ary, v)
+			continue
+		}
+
+		dup := false
+		for _, s := range seen {
+			if reflect.DeepEqual(s, val) {
+				dup = true
+				break
+			}
+		}
+
+		if !dup {
+			seen = append(seen, val)
+			ary = append(ary, v)
+		}
+	}
+
+	*n = ary
+}
+
 func findContainer(c Container, path *OpPath) (Container, string, error) {
 	parts, key, err := path.Decompose()
 	if err != nil {
@@ -135,22 +250,50 @@ func findContainer(c Container, path *OpPath) (Container, string, error) {
 
 	foundContainer := c
 
-	for _, part := range parts {
-		node, err := foundContainer.Get(decodePatchKey(part))
-		if err != nil {
-			return nil, "", err
+	for i, part := range parts {
+		prefix := strings.Join(parts[:i], "/")
+		if prefix != "" {
+			prefix = "/" + prefix
+		}
+
+		if foundContainer == nil {
+			return nil, "", missingKey(nil, prefix, decodePatchKey(part))
 		}
 
-		if node == nil {
-			return nil, "", fmt.Errorf("path does not exist: %s", path)
+		node, err := foundContainer.Get(decodePatchKey(part))
+		if err != nil || node == nil {
+			return nil, "", missingKey(foundContainer, prefix, decodePatchKey(part))
 		}
 
 		foundContainer = node.Container()
 	}
 
+	if foundContainer == nil {
+		return nil, "", missingKey(nil, "/"+strings.Join(parts, "/"), decodePatchKey(key))
+	}
+
 	return foundContainer, decodePatchKey(key), nil
 }
 
+func findSlice(c Container, path *OpPath) (*nodeSlice, error) {
+	con, key, err := findContainer(c, path)
+	if err != nil {
+		return nil, fmt.Errorf("path does not exist: %s: %s", path, err)
+	}
+
+	node, err := con.Get(key)
+	if err != nil || node == nil {
+		return nil, fmt.Errorf("path does not exist: %s: %s", path, missingKey(con, parentPath(*path), key))
+	}
+
+	ary, ok := node.Container().(*nodeSlice)
+	if !ok {
+		return nil, fmt.Errorf("path does not point at an array: %s", path)
+	}
+
+	return ary, nil
+}
+
 // From http://tools.ietf.org/html/rfc6901#section-4 :
 //
 // Evaluation of each reference token begins by decoding any escaped
@@ -160,8 +303,24 @@ func findContainer(c Container, path *OpPath) (Container, string, error) {
 
 var (
 	rfc6901Decoder = strings.NewReplacer("~1", "/", "~0", "~")
+	rfc6901Encoder = strings.NewReplacer("~", "~0", "/", "~1")
 )
 
 func decodePatchKey(k string) string {
 	return rfc6901Decoder.Replace(k)
 }
+
+func encodePatchKey(k string) string {
+	return rfc6901Encoder.Replace(k)
+}
+
+// parentPath returns the path of the container holding the last segment of
+// the path
+func parentPath(path OpPath) string {
+	parts, _, err := path.Decompose()
+	if err != nil || len(parts) == 0 {
+		return ""
+	}
+
+	return "/" + strings.Join(parts, "/")
+}
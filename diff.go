@@ -0,0 +1,300 @@
+package yamlpatch
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// DiffOptions configures a unified diff
+type DiffOptions struct {
+	// FromFile and ToFile label the two documents in the diff header. They
+	// default to "a" and "b".
+	FromFile string
+	ToFile   string
+	// Context is the number of unchanged lines shown around each change. It
+	// defaults to 3.
+	Context int
+	// Color highlights the diff with ANSI escape codes
+	Color bool
+}
+
+const (
+	defaultDiffContext = 3
+
+	colorReset = "\x1b[0m"
+	colorBold  = "\x1b[1m"
+	colorRed   = "\x1b[31m"
+	colorGreen = "\x1b[32m"
+	colorCyan  = "\x1b[36m"
+)
+
+// DryRun applies the patch to the document and returns a unified diff between
+// the document and the result, rather than the result itself. The document is
+// marshaled before it is compared so that only changes made by the patch are
+// shown. The diff is empty when the patch makes no changes.
+func (p Patch) DryRun(doc []byte, opts DiffOptions) (string, error) {
+	before, err := Patch(nil).Apply(doc)
+	if err != nil {
+		return "", err
+	}
+
+	after, err := p.Apply(doc)
+	if err != nil {
+		return "", err
+	}
+
+	return UnifiedDiff(before, after, opts), nil
+}
+
+type diffOp byte
+
+const (
+	diffEqual  diffOp = ' '
+	diffDelete diffOp = '-'
+	diffInsert diffOp = '+'
+)
+
+type diffLine struct {
+	op   diffOp
+	text string
+}
+
+// UnifiedDiff returns a unified diff of the lines of two documents, or an
+// empty string if they are equal
+func UnifiedDiff(a, b []byte, opts DiffOptions) string {
+	if opts.FromFile == "" {
+		opts.FromFile = "a"
+	}
+	if opts.ToFile == "" {
+		opts.ToFile = "b"
+	}
+	if opts.Context <= 0 {
+		opts.Context = defaultDiffContext
+	}
+
+	lines := diffLines(splitLines(a), splitLines(b))
+
+	var buf bytes.Buffer
+	for _, h := range hunks(lines, opts.Context) {
+		if buf.Len() == 0 {
+			writeColored(&buf, opts.Color, colorBold, "--- "+opts.FromFile)
+			writeColored(&buf, opts.Color, colorBold, "+++ "+opts.ToFile)
+		}
+
+		writeColored(&buf, opts.Color, colorCyan, h.header())
+
+		for _, l := range lines[h.start:h.end] {
+			color := ""
+			switch l.op {
+			case diffDelete:
+				color = colorRed
+			case diffInsert:
+				color = colorGreen
+			}
+
+			writeColored(&buf, opts.Color && color != "", color, string(l.op)+l.text)
+		}
+	}
+
+	return buf.String()
+}
+
+func writeColored(buf *bytes.Buffer, color bool, code, line string) {
+	if color {
+		fmt.Fprintf(buf, "%s%s%s\n", code, line, colorReset)
+		return
+	}
+
+	fmt.Fprintln(buf, line)
+}
+
+func splitLines(bs []byte) []string {
+	s := strings.TrimSuffix(string(bs), "\n")
+	if s == "" {
+		return nil
+	}
+
+	return strings.Split(s, "\n")
+}
+
+// diffLines returns a shortest edit script turning a into b, using Myers'
+// algorithm
+func diffLines(a, b []string) []diffLine {
+	n, m := len(a), len(b)
+	max := n + m
+	offset := max + 1
+	v := make([]int, 2*max+3)
+
+	var trace [][]int
+	var d int
+
+search:
+	for d = 0; d <= max; d++ {
+		trace = append(trace, append([]int(nil), v...))
+
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+
+			v[offset+k] = x
+
+			if x >= n && y >= m {
+				break search
+			}
+		}
+	}
+
+	var lines []diffLine
+	x, y := n, m
+
+	for ; d > 0; d-- {
+		v := trace[d]
+		k := x - y
+
+		var prevK int
+		if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+
+		prevX := v[offset+prevK]
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			lines = append(lines, diffLine{diffEqual, a[x-1]})
+			x--
+			y--
+		}
+
+		if x == prevX {
+			lines = append(lines, diffLine{diffInsert, b[y-1]})
+			y--
+		} else {
+			lines = append(lines, diffLine{diffDelete, a[x-1]})
+			x--
+		}
+	}
+
+	for x > 0 && y > 0 {
+		lines = append(lines, diffLine{diffEqual, a[x-1]})
+		x--
+		y--
+	}
+
+	for i, j := 0, len(lines)-1; i < j; i, j = i+1, j-1 {
+		lines[i], lines[j] = lines[j], lines[i]
+	}
+
+	return lines
+}
+
+// hunk is a range of an edit script along with the line numbers it starts at
+// in each document
+type hunk struct {
+	start, end       int
+	fromLine, toLine int
+	fromLen, toLen   int
+}
+
+func (h hunk) header() string {
+	return fmt.Sprintf("@@ -%s +%s @@", hunkRange(h.fromLine, h.fromLen), hunkRange(h.toLine, h.toLen))
+}
+
+func hunkRange(line, length int) string {
+	switch length {
+	case 0:
+		return fmt.Sprintf("%d,0", line-1)
+	case 1:
+		return fmt.Sprintf("%d", line)
+	}
+
+	return fmt.Sprintf("%d,%d", line, length)
+}
+
+// hunks groups the changes in an edit script with the given number of lines
+// of context around them, merging groups whose context overlaps
+func hunks(lines []diffLine, context int) []hunk {
+	var hs []hunk
+
+	for i := 0; i < len(lines); {
+		if lines[i].op == diffEqual {
+			i++
+			continue
+		}
+
+		start := i - context
+		if start < 0 {
+			start = 0
+		}
+
+		end := i
+		for end < len(lines) {
+			if lines[end].op != diffEqual {
+				end++
+				continue
+			}
+
+			next := end
+			for next < len(lines) && lines[next].op == diffEqual {
+				next++
+			}
+
+			if next == len(lines) || next-end > 2*context {
+				break
+			}
+
+			end = next
+		}
+
+		i = end
+		end += context
+		if end > len(lines) {
+			end = len(lines)
+		}
+
+		if len(hs) > 0 && start <= hs[len(hs)-1].end {
+			start = hs[len(hs)-1].start
+			hs = hs[:len(hs)-1]
+		}
+
+		hs = append(hs, newHunk(lines, start, end))
+	}
+
+	return hs
+}
+
+func newHunk(lines []diffLine, start, end int) hunk {
+	h := hunk{start: start, end: end, fromLine: 1, toLine: 1}
+
+	for _, l := range lines[:start] {
+		if l.op != diffInsert {
+			h.fromLine++
+		}
+		if l.op != diffDelete {
+			h.toLine++
+		}
+	}
+
+	for _, l := range lines[start:end] {
+		if l.op != diffInsert {
+			h.fromLen++
+		}
+		if l.op != diffDelete {
+			h.toLen++
+		}
+	}
+
+	return h
+}
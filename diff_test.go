@@ -0,0 +1,95 @@
+package yamlpatch_test
+
+import (
+	yamlpatch "github.com/krishicks/yaml-patch"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("UnifiedDiff", func() {
+	It("returns an empty diff for equal documents", func() {
+		Expect(yamlpatch.UnifiedDiff([]byte("a: 1\n"), []byte("a: 1\n"), yamlpatch.DiffOptions{})).To(BeEmpty())
+	})
+
+	It("returns hunks with context around each change", func() {
+		a := []byte("a\nb\nc\nd\ne\nf\ng\nh\ni\nj\nk\nl\n")
+		b := []byte("a\nB\nc\nd\ne\nf\ng\nh\ni\nj\nk\nl\nm\n")
+
+		Expect(yamlpatch.UnifiedDiff(a, b, yamlpatch.DiffOptions{Context: 2})).To(Equal(`--- a
++++ b
+@@ -1,4 +1,4 @@
+ a
+-b
++B
+ c
+ d
+@@ -11,2 +11,3 @@
+ k
+ l
++m
+`))
+	})
+
+	It("merges hunks whose context overlaps", func() {
+		a := []byte("a\nb\nc\nd\n")
+		b := []byte("A\nb\nc\nD\n")
+
+		Expect(yamlpatch.UnifiedDiff(a, b, yamlpatch.DiffOptions{FromFile: "in", ToFile: "out"})).To(Equal(`--- in
++++ out
+@@ -1,4 +1,4 @@
+-a
++A
+ b
+ c
+-d
++D
+`))
+	})
+
+	It("colorizes the diff", func() {
+		Expect(yamlpatch.UnifiedDiff([]byte("a\n"), []byte("b\n"), yamlpatch.DiffOptions{Color: true})).To(Equal(
+			"\x1b[1m--- a\x1b[0m\n" +
+				"\x1b[1m+++ b\x1b[0m\n" +
+				"\x1b[36m@@ -1 +1 @@\x1b[0m\n" +
+				"\x1b[31m-a\x1b[0m\n" +
+				"\x1b[32m+b\x1b[0m\n",
+		))
+	})
+})
+
+var _ = Describe("DryRun", func() {
+	It("returns a diff of the changes made by the patch", func() {
+		patch, err := yamlpatch.DecodePatch([]byte(`---
+- op: replace
+  path: /foo
+  value: baz
+`))
+		Expect(err).NotTo(HaveOccurred())
+
+		diff, err := patch.DryRun([]byte("---\nfoo:   bar\nqux: [a, b]\n"), yamlpatch.DiffOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(diff).To(Equal(`--- a
++++ b
+@@ -1,4 +1,4 @@
+-foo: bar
++foo: baz
+ qux:
+ - a
+ - b
+`))
+	})
+
+	It("returns an empty diff when the patch makes no changes", func() {
+		patch, err := yamlpatch.DecodePatch([]byte(`---
+- op: test
+  path: /foo
+  value: bar
+`))
+		Expect(err).NotTo(HaveOccurred())
+
+		diff, err := patch.DryRun([]byte("foo:   bar\n"), yamlpatch.DiffOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(diff).To(BeEmpty())
+	})
+})
@@ -0,0 +1,168 @@
+package yamlpatch
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// ApplyError is the failure of a single operation in a patch
+type ApplyError struct {
+	// Index is the position of the operation in the patch
+	Index int
+	Op    Op
+	// Path is the path of the operation, expanded if it used extended syntax
+	Path OpPath
+	// Source is where the operation was decoded from
+	Source Source
+	// DocPath is the longest prefix of Path that exists in the document when
+	// the operation failed, and DocLine is the line it is on in the document
+	// the patch was applied to. DocLine is empty when earlier operations
+	// changed the value at DocPath, and both are empty when none of the path
+	// beneath the root exists.
+	DocPath OpPath
+	DocLine int
+	Err     error
+
+	// docValue is the value at DocPath when the operation failed
+	docValue interface{}
+}
+
+func (e *ApplyError) Error() string {
+	var at string
+	if e.Source.Line != 0 {
+		at = " at " + e.Source.String()
+	}
+
+	msg := fmt.Sprintf("operation %d (%s %s)%s: %s", e.Index, e.Op, e.Path, at, e.Err)
+	switch {
+	case e.DocLine != 0:
+		msg += fmt.Sprintf(" (closest existing path in the document: %s, line %d)", e.DocPath, e.DocLine)
+	case e.DocPath != "":
+		msg += fmt.Sprintf(" (closest existing path in the document: %s)", e.DocPath)
+	}
+
+	return msg
+}
+
+// Unwrap returns the reason the operation failed
+func (e *ApplyError) Unwrap() error {
+	return e.Err
+}
+
+// ApplyErrors is every failure of a patch applied with ContinueOnError
+type ApplyErrors []*ApplyError
+
+func (e ApplyErrors) Error() string {
+	lines := make([]string, len(e))
+	for i, err := range e {
+		lines[i] = "\t" + err.Error()
+	}
+
+	noun := "operations"
+	if len(e) == 1 {
+		noun = "operation"
+	}
+
+	return fmt.Sprintf("%d %s failed:\n%s", len(e), noun, strings.Join(lines, "\n"))
+}
+
+// closestPath returns the longest prefix of the path that exists beneath the
+// root of the container, and a copy of the value at it
+func closestPath(c Container, path OpPath) (OpPath, interface{}) {
+	parts := strings.Split(string(path), "/")[1:]
+
+	for i := len(parts); i > 0; i-- {
+		prefix := OpPath("/" + strings.Join(parts[:i], "/"))
+		if node := lookupNode(c, prefix); node != nil {
+			return prefix, copyValue(node.Value())
+		}
+	}
+
+	return "", nil
+}
+
+// locateErrors finds the line in the document of the closest existing path
+// of each failed operation, so that the error can point at it. The line is
+// only found when the value at the path is the one in the document, as
+// otherwise earlier operations may have moved or replaced it.
+func locateErrors(doc []byte, err error) {
+	var errs ApplyErrors
+	switch e := err.(type) {
+	case *ApplyError:
+		errs = ApplyErrors{e}
+	case ApplyErrors:
+		errs = e
+	default:
+		return
+	}
+
+	var root yamlv3.Node
+	if yamlv3.Unmarshal(doc, &root) != nil || len(root.Content) == 0 {
+		return
+	}
+
+	var iface interface{}
+	if yaml.Unmarshal(doc, &iface) != nil {
+		return
+	}
+	original := NewNode(&iface).Container()
+
+	for _, e := range errs {
+		if e.DocPath == "" || original == nil {
+			continue
+		}
+
+		node := lookupNode(original, e.DocPath)
+		if node == nil || !reflect.DeepEqual(node.Value(), e.docValue) {
+			continue
+		}
+
+		_, e.DocLine = documentLine(root.Content[0], e.DocPath)
+	}
+}
+
+// documentLine returns the longest prefix of the path that exists beneath the
+// node, and the line it is on
+func documentLine(n *yamlv3.Node, path OpPath) (OpPath, int) {
+	var found []string
+	var line int
+
+	parts := strings.Split(string(path), "/")[1:]
+
+walk:
+	for _, part := range parts {
+		switch n.Kind {
+		case yamlv3.MappingNode:
+			key := decodePatchKey(part)
+			for i := 0; i+1 < len(n.Content); i += 2 {
+				if n.Content[i].Value == key {
+					line = n.Content[i].Line
+					n = n.Content[i+1]
+					found = append(found, part)
+					continue walk
+				}
+			}
+		case yamlv3.SequenceNode:
+			i, err := strconv.Atoi(part)
+			if err == nil && i >= 0 && i < len(n.Content) {
+				n = n.Content[i]
+				line = n.Line
+				found = append(found, part)
+				continue walk
+			}
+		}
+
+		break
+	}
+
+	if len(found) == 0 {
+		return "", 0
+	}
+
+	return OpPath("/" + strings.Join(found, "/")), line
+}
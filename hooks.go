@@ -0,0 +1,136 @@
+package yamlpatch
+
+// Hooks are notified of each operation performed when a patch is applied with
+// ApplyWithOptions
+type Hooks interface {
+	// BeforeOp is called before an operation is performed at a path. Returning
+	// an error fails the operation without performing it.
+	BeforeOp(e OpEvent) error
+	// AfterOp is called after an operation is performed at a path
+	AfterOp(e OpEvent)
+	// OnError is called when an operation fails
+	OnError(e OpEvent, err error)
+}
+
+// OpEvent describes an operation performed at a single path
+type OpEvent struct {
+	// Index is the position of the operation in the patch
+	Index int
+	// Op is the operation as written in the patch
+	Op Operation
+	// Path is the concrete path the operation is performed at, which differs
+	// from Op.Path when it uses extended syntax
+	Path OpPath
+	// Old and New are copies of the values at the path before and after the
+	// operation, or nil if there was no value. New is only set for AfterOp.
+	Old *Node
+	New *Node
+}
+
+// hooksObserver is an observer that calls Hooks
+type hooksObserver struct {
+	hooks Hooks
+	op    Operation
+	old   *Node
+}
+
+func (h *hooksObserver) expanded(index int, op *Operation, err error) {
+	h.op = *op
+
+	if err != nil {
+		h.hooks.OnError(OpEvent{Index: index, Op: h.op, Path: op.Path}, err)
+	}
+}
+
+func (h *hooksObserver) beforeOp(index int, op *Operation, c Container) error {
+	h.old = lookupNode(c, op.Path).clone()
+
+	return h.hooks.BeforeOp(OpEvent{
+		Index: index,
+		Op:    h.op,
+		Path:  op.Path,
+		Old:   h.old,
+	})
+}
+
+func (h *hooksObserver) afterOp(index int, op *Operation, c Container, err error) {
+	e := OpEvent{
+		Index: index,
+		Op:    h.op,
+		Path:  op.Path,
+		Old:   h.old,
+	}
+
+	if err != nil {
+		h.hooks.OnError(e, err)
+		return
+	}
+
+	e.Path, e.New = performedAt(c, op)
+	h.hooks.AfterOp(e)
+}
+
+// foreachObserver passes the operations nested in a foreach on to the
+// observers of the patch as changes made by the foreach at index, with their
+// paths in the whole document
+type foreachObserver struct {
+	observer
+	index  int
+	doc    Container
+	prefix OpPath
+}
+
+// expanded keeps the foreach as the operation being observed. A nested
+// operation that fails to expand is reported as failing at its path.
+func (f *foreachObserver) expanded(index int, op *Operation, err error) {
+	if err != nil {
+		abs := f.absolute(op)
+		f.observer.beforeOp(f.index, abs, f.doc)
+		f.observer.afterOp(f.index, abs, f.doc, err)
+	}
+}
+
+func (f *foreachObserver) beforeOp(index int, op *Operation, c Container) error {
+	return f.observer.beforeOp(f.index, f.absolute(op), f.doc)
+}
+
+func (f *foreachObserver) afterOp(index int, op *Operation, c Container, err error) {
+	f.observer.afterOp(f.index, f.absolute(op), f.doc, err)
+}
+
+// absolute returns a copy of the nested operation with its paths prefixed by
+// the path of the node the foreach is performing it on
+func (f *foreachObserver) absolute(op *Operation) *Operation {
+	abs := *op
+	abs.Path = f.prefix + op.Path
+	if op.From != "" {
+		abs.From = f.prefix + op.From
+	}
+
+	return &abs
+}
+
+// observers notifies each of a list of observers in turn
+type observers []observer
+
+func (obs observers) expanded(index int, op *Operation, err error) {
+	for _, o := range obs {
+		o.expanded(index, op, err)
+	}
+}
+
+func (obs observers) beforeOp(index int, op *Operation, c Container) error {
+	for _, o := range obs {
+		if err := o.beforeOp(index, op, c); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (obs observers) afterOp(index int, op *Operation, c Container, err error) {
+	for _, o := range obs {
+		o.afterOp(index, op, c, err)
+	}
+}
@@ -0,0 +1,144 @@
+package yamlpatch_test
+
+import (
+	"errors"
+	"fmt"
+
+	yamlpatch "github.com/krishicks/yaml-patch"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type recordingHooks struct {
+	events []string
+	deny   yamlpatch.OpPath
+}
+
+func (h *recordingHooks) BeforeOp(e yamlpatch.OpEvent) error {
+	h.events = append(h.events, fmt.Sprintf("before %d %s %s old=%v", e.Index, e.Op.Op, e.Path, nodeValue(e.Old)))
+	if e.Path == h.deny {
+		return errors.New("denied")
+	}
+	return nil
+}
+
+func (h *recordingHooks) AfterOp(e yamlpatch.OpEvent) {
+	h.events = append(h.events, fmt.Sprintf("after %d %s %s old=%v new=%v", e.Index, e.Op.Path, e.Path, nodeValue(e.Old), nodeValue(e.New)))
+}
+
+func (h *recordingHooks) OnError(e yamlpatch.OpEvent, err error) {
+	h.events = append(h.events, fmt.Sprintf("error %d %s: %s", e.Index, e.Path, err))
+}
+
+func nodeValue(n *yamlpatch.Node) interface{} {
+	if n == nil {
+		return "<absent>"
+	}
+	return n.Value()
+}
+
+var _ = Describe("Hooks", func() {
+	var (
+		hooks *recordingHooks
+		patch yamlpatch.Patch
+		doc   []byte
+	)
+
+	BeforeEach(func() {
+		hooks = &recordingHooks{}
+
+		var err error
+		patch, err = yamlpatch.DecodePatch([]byte(`---
+- op: replace
+  path: /jobs/tier=web/serial
+  value: true
+- op: add
+  path: /jobs/-
+  value: {name: job3}
+- op: remove
+  path: /missing
+`))
+		Expect(err).NotTo(HaveOccurred())
+
+		doc = []byte(`---
+jobs:
+- name: job1
+  tier: web
+  serial: false
+- name: job2
+  tier: web
+  serial: false
+`)
+	})
+
+	It("are called around each operation at each concrete path", func() {
+		_, err := patch.ApplyWithOptions(doc, yamlpatch.ApplyOptions{Hooks: hooks})
+		Expect(err).To(HaveOccurred())
+
+		Expect(hooks.events).To(Equal([]string{
+			"before 0 replace /jobs/0/serial old=false",
+			"after 0 /jobs/tier=web/serial /jobs/0/serial old=false new=true",
+			"before 0 replace /jobs/1/serial old=false",
+			"after 0 /jobs/tier=web/serial /jobs/1/serial old=false new=true",
+			"before 1 add /jobs/- old=<absent>",
+			"after 1 /jobs/- /jobs/2 old=<absent> new=map[name:job3]",
+			"before 2 remove /missing old=<absent>",
+			"error 2 /missing: Unable to remove nonexistent key: missing",
+		}))
+	})
+
+	It("report a removed array element as absent", func() {
+		patch, err := yamlpatch.DecodePatch([]byte(`---
+- op: remove
+  path: /list/0
+`))
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = patch.ApplyWithOptions([]byte("list: [a, b]\n"), yamlpatch.ApplyOptions{Hooks: hooks})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(hooks.events).To(Equal([]string{
+			"before 0 remove /list/0 old=a",
+			"after 0 /list/0 /list/0 old=a new=<absent>",
+		}))
+	})
+
+	It("are called for each operation nested in a foreach at its path in the document", func() {
+		patch, err := yamlpatch.DecodePatch([]byte(`---
+- op: foreach
+  path: /jobs/tier=web
+  ops:
+  - op: replace
+    path: /serial
+    value: true
+`))
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = patch.ApplyWithOptions(doc, yamlpatch.ApplyOptions{Hooks: hooks})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(hooks.events).To(Equal([]string{
+			"before 0 foreach /jobs/0/serial old=false",
+			"after 0 /jobs/tier=web /jobs/0/serial old=false new=true",
+			"before 0 foreach /jobs/1/serial old=false",
+			"after 0 /jobs/tier=web /jobs/1/serial old=false new=true",
+		}))
+	})
+
+	It("fail an operation when BeforeOp returns an error", func() {
+		hooks.deny = "/jobs/1/serial"
+
+		bs, err := patch.ApplyWithOptions(doc, yamlpatch.ApplyOptions{Hooks: hooks, ContinueOnError: true})
+		Expect(err).To(HaveOccurred())
+
+		errs, ok := err.(yamlpatch.ApplyErrors)
+		Expect(ok).To(BeTrue())
+		Expect(errs).To(HaveLen(2))
+		Expect(errs[0].Path).To(Equal(yamlpatch.OpPath("/jobs/1/serial")))
+		Expect(errs[0].Err).To(MatchError("denied"))
+
+		Expect(hooks.events).To(ContainElement("error 0 /jobs/1/serial: denied"))
+		Expect(string(bs)).To(ContainSubstring("serial: false"))
+	})
+})
@@ -0,0 +1,128 @@
+package yamlpatch
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// IdempotencyIssue is an operation that does something different when its
+// patch is applied a second time
+type IdempotencyIssue struct {
+	// Index is the position of the operation in the patch
+	Index int
+	// Op is the operation as written
+	Op Operation
+	// Path is the concrete path at which the operation changed the document
+	// again, or the operation's path if it failed to expand
+	Path OpPath
+	// Err is set when the operation fails on the second application, and
+	// otherwise it changed the document again
+	Err error
+	// Suggestion is an idempotent alternative to the operation, if one is
+	// known
+	Suggestion string
+}
+
+func (i IdempotencyIssue) String() string {
+	var at string
+	if i.Op.Source.Line != 0 {
+		at = " at " + i.Op.Source.String()
+	}
+
+	what := "changes the document again"
+	if i.Err != nil {
+		what = fmt.Sprintf("fails when applied again: %s", i.Err)
+	}
+
+	msg := fmt.Sprintf("operation %d (%s %s)%s %s", i.Index, i.Op.Op, i.Path, at, what)
+	if i.Suggestion != "" {
+		msg += "; " + i.Suggestion
+	}
+
+	return msg
+}
+
+// IsIdempotent applies the patch to the document, then applies it again to
+// the result, and returns whether the second application left the result
+// unchanged. The operations that failed the second time, and when the result
+// changed the operations that changed it, are returned along with
+// suggestions of idempotent alternatives. An error is returned if the patch
+// fails the first time.
+func (p Patch) IsIdempotent(doc []byte) (bool, []IdempotencyIssue, error) {
+	once, err := p.Apply(doc)
+	if err != nil {
+		return false, nil, err
+	}
+
+	twice, trace, err := p.ApplyWithTrace(once, ApplyOptions{ContinueOnError: true})
+	if _, ok := err.(ApplyErrors); err != nil && !ok {
+		return false, nil, err
+	}
+
+	// operations that each change the result can still leave it as it was,
+	// e.g. an add followed by a replace of the same path
+	changed := !bytes.Equal(once, twice)
+
+	var issues []IdempotencyIssue
+	for _, ot := range trace {
+		op := p[ot.Index]
+
+		if ot.Err != nil && len(ot.Paths) == 0 {
+			issues = append(issues, IdempotencyIssue{Index: ot.Index, Op: op, Path: ot.Path, Err: ot.Err, Suggestion: suggestIdempotent(op, true)})
+			continue
+		}
+
+		for _, pt := range ot.Paths {
+			if pt.Err == nil && (pt.NoOp || !changed) {
+				continue
+			}
+
+			issues = append(issues, IdempotencyIssue{Index: ot.Index, Op: op, Path: pt.Path, Err: pt.Err, Suggestion: suggestIdempotent(op, pt.Err != nil)})
+		}
+	}
+
+	return len(issues) == 0, issues, nil
+}
+
+// suggestIdempotent returns an idempotent alternative to an operation that
+// changed the document again, or failed when applied again
+func suggestIdempotent(op Operation, failed bool) string {
+	if op.Op == opRemove {
+		if _, key, _ := op.Path.Decompose(); isIndex(key) {
+			return "use remove-value to remove the element by its value, which does nothing once it is gone"
+		}
+	}
+
+	if failed {
+		switch op.Op {
+		case opRemove:
+			return "removing a key fails once it is gone, so the ops file should only be applied to documents that still have it"
+		case opMove, opRename:
+			return "replace the value at the destination instead, removing the original separately"
+		}
+
+		return ""
+	}
+
+	switch op.Op {
+	case opAdd, opCopy:
+		if _, key, _ := op.Path.Decompose(); isIndex(key) {
+			return "use add-unique to add the value only if the array does not already contain it"
+		}
+	case opExtend:
+		return "use add-unique for each value, or merge with the merge strategy and a key"
+	case opMerge:
+		if op.Strategy == ListAppend || op.Strategy == ListPrepend {
+			return fmt.Sprintf("use the %s strategy with a key to update matching elements instead of adding them again", ListMergeByKey)
+		}
+	case opIncrement, opDecrement, opMultiply:
+		return "use replace with the resulting value"
+	case opTransform:
+		if strings.HasPrefix(op.Function, "add-") || op.Function == "format" {
+			return "use replace with the resulting value, or test the value before transforming it"
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,161 @@
+package yamlpatch_test
+
+import (
+	yamlpatch "github.com/krishicks/yaml-patch"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("IsIdempotent", func() {
+	doc := []byte(`---
+instances: 1
+labels:
+  team: core
+tags: [a, b]
+jobs:
+- name: job1
+  plan:
+  - get: A
+`)
+
+	check := func(ops string) (bool, []yamlpatch.IdempotencyIssue) {
+		patch, err := yamlpatch.DecodePatch([]byte(ops))
+		Expect(err).NotTo(HaveOccurred())
+
+		idempotent, issues, err := patch.IsIdempotent(doc)
+		Expect(err).NotTo(HaveOccurred())
+		return idempotent, issues
+	}
+
+	DescribeTable(
+		"reports no issues for",
+		func(ops string) {
+			idempotent, issues := check(ops)
+			Expect(idempotent).To(BeTrue())
+			Expect(issues).To(BeEmpty())
+		},
+		Entry("a replace", `---
+- op: replace
+  path: /instances
+  value: 3
+`),
+		Entry("an add to a map", `---
+- op: add
+  path: /labels/tier
+  value: web
+`),
+		Entry("an add-unique", `---
+- op: add-unique
+  path: /tags
+  value: c
+`),
+		Entry("a merge by key", `---
+- op: merge
+  path: /jobs
+  strategy: merge
+  key: name
+  value:
+  - name: job1
+    serial: true
+`),
+		Entry("a remove-value", `---
+- op: remove-value
+  path: /tags
+  value: a
+`),
+		Entry("operations that change the result again but leave it as it was", `---
+- op: add
+  path: /a
+  value: 1
+- op: replace
+  path: /a
+  value: 2
+`),
+		Entry("an extended path", `---
+- op: add
+  path: /jobs/name=job1/plan/get=A/trigger
+  value: true
+`),
+	)
+
+	DescribeTable(
+		"reports",
+		func(ops string, expected string) {
+			idempotent, issues := check(ops)
+			Expect(idempotent).To(BeFalse())
+			Expect(issues).To(HaveLen(1))
+			Expect(issues[0].String()).To(Equal(expected))
+		},
+		Entry("an append, suggesting add-unique", `---
+- op: add
+  path: /tags/-
+  value: c
+`,
+			`operation 0 (add /tags/3) at line 2 changes the document again; use add-unique to add the value only if the array does not already contain it`,
+		),
+		Entry("an extend, suggesting add-unique", `---
+- op: extend
+  path: /tags
+  value: [c]
+`,
+			`operation 0 (extend /tags) at line 2 changes the document again; use add-unique for each value, or merge with the merge strategy and a key`,
+		),
+		Entry("an increment, suggesting replace", `---
+- op: increment
+  path: /instances
+  value: 2
+`,
+			`operation 0 (increment /instances) at line 2 changes the document again; use replace with the resulting value`,
+		),
+		Entry("a merge that appends, suggesting a key", `---
+- op: merge
+  path: /jobs
+  strategy: append
+  value:
+  - name: job2
+`,
+			`operation 0 (merge /jobs) at line 2 changes the document again; use the merge strategy with a key to update matching elements instead of adding them again`,
+		),
+		Entry("a removed array element, suggesting remove-value", `---
+- op: remove
+  path: /tags/0
+`,
+			`operation 0 (remove /tags/0) at line 2 changes the document again; use remove-value to remove the element by its value, which does nothing once it is gone`,
+		),
+		Entry("a removed key that is gone", `---
+- op: remove
+  path: /labels/team
+`,
+			`operation 0 (remove /labels/team) at line 2 fails when applied again: Unable to remove nonexistent key: team; removing a key fails once it is gone, so the ops file should only be applied to documents that still have it`,
+		),
+	)
+
+	It("reports only the operations that are not idempotent", func() {
+		idempotent, issues := check(`---
+- op: replace
+  path: /instances
+  value: 3
+- op: add
+  path: /jobs/name=job1/plan/-
+  value:
+    get: B
+`)
+		Expect(idempotent).To(BeFalse())
+		Expect(issues).To(HaveLen(1))
+		Expect(issues[0].Index).To(Equal(1))
+		Expect(issues[0].Path).To(Equal(yamlpatch.OpPath("/jobs/0/plan/2")))
+	})
+
+	It("returns an error when the patch fails the first time", func() {
+		patch, err := yamlpatch.DecodePatch([]byte(`---
+- op: remove
+  path: /nonexistent
+`))
+		Expect(err).NotTo(HaveOccurred())
+
+		_, _, err = patch.IsIdempotent(doc)
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,224 @@
+package yamlpatch
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Loader provides the files and environment variables that operations can
+// take their values from
+type Loader interface {
+	ReadFile(path string) ([]byte, error)
+	LookupEnv(name string) (string, bool)
+}
+
+// ValueLoader can be implemented by a Loader to read the files that values are
+// taken from differently than ops files, e.g. without preprocessing that is
+// only meant for ops files
+type ValueLoader interface {
+	ReadValueFile(path string) ([]byte, error)
+}
+
+// OSLoader is a Loader backed by the filesystem and the environment of the
+// current process
+type OSLoader struct{}
+
+// ReadFile reads the file at path from disk
+func (OSLoader) ReadFile(path string) ([]byte, error) {
+	return ioutil.ReadFile(path)
+}
+
+// LookupEnv returns the value of the named environment variable
+func (OSLoader) LookupEnv(name string) (string, bool) {
+	return os.LookupEnv(name)
+}
+
+// Encodings
+const (
+	encodingBase64 = "base64"
+)
+
+// DecodePatchFile reads the ops file at path using the loader and decodes it
+// as with DecodePatch. Values taken from files are resolved relative to the
+// directory containing the ops file, and included ops files are expanded in
+// place.
+func DecodePatchFile(loader Loader, path string) (Patch, error) {
+	return decodePatchFile(loader, path, nil, nil)
+}
+
+// decodePatchFile decodes the ops file at path, substituting the given
+// variables. The chain holds the ops files that included it, in order.
+func decodePatchFile(loader Loader, path string, vars map[string]interface{}, chain []string) (Patch, error) {
+	for _, included := range chain {
+		if included == path {
+			return nil, fmt.Errorf("include cycle: %s", strings.Join(append(chain, path), " -> "))
+		}
+	}
+
+	chain = append(chain[:len(chain):len(chain)], path)
+	errorf := func(format string, args ...interface{}) error {
+		return fmt.Errorf("%s: %s", strings.Join(chain, " -> "), fmt.Sprintf(format, args...))
+	}
+
+	bs, err := loader.ReadFile(path)
+	if err != nil {
+		return nil, errorf("%s", err)
+	}
+
+	bs, err = substituteVars(bs, vars)
+	if err != nil {
+		return nil, errorf("%s", err)
+	}
+
+	decoded, err := DecodePatch(bs)
+	if err != nil {
+		return nil, errorf("%s", err)
+	}
+
+	for i := range decoded {
+		decoded[i].Source.File = path
+	}
+
+	return expandPatch(loader, filepath.Dir(path), decoded, chain, errorf)
+}
+
+// expandPatch loads the values of the operations and expands the ops files
+// they include, including those of operations nested in a foreach
+func expandPatch(loader Loader, dir string, decoded Patch, chain []string, errorf func(string, ...interface{}) error) (Patch, error) {
+	var p Patch
+	for i, op := range decoded {
+		if op.Include == "" {
+			err := op.loadValue(loader, dir)
+			if err != nil {
+				return nil, errorf("operation %d: %s", i, err)
+			}
+
+			if op.Ops != nil {
+				op.Ops, err = expandPatch(loader, dir, op.Ops, chain, errorf)
+				if err != nil {
+					return nil, err
+				}
+			}
+
+			p = append(p, op)
+			continue
+		}
+
+		if op.Op != "" {
+			return nil, errorf("operation %d: include cannot be combined with op", i)
+		}
+
+		included := op.Include
+		if !filepath.IsAbs(included) {
+			included = filepath.Join(dir, included)
+		}
+
+		ops, err := decodePatchFile(loader, included, op.Vars, chain)
+		if err != nil {
+			return nil, err
+		}
+
+		p = append(p, ops...)
+	}
+
+	return p, nil
+}
+
+// substituteVars replaces each ((name)) in the ops file with the value of the
+// variable, leaving placeholders for undefined variables as they are
+func substituteVars(bs []byte, vars map[string]interface{}) ([]byte, error) {
+	if len(vars) == 0 {
+		return bs, nil
+	}
+
+	var pairs []string
+	for name, val := range vars {
+		switch val.(type) {
+		case map[interface{}]interface{}, []interface{}:
+			return nil, fmt.Errorf("variable is not a scalar: %s", name)
+		}
+
+		pairs = append(pairs, "(("+name+"))", fmt.Sprint(val))
+	}
+
+	return []byte(strings.NewReplacer(pairs...).Replace(string(bs))), nil
+}
+
+// loadValue sets the operation's Value from its ValueFile or ValueEnv, after
+// which the operation no longer refers to the file or environment variable
+func (o *Operation) loadValue(loader Loader, dir string) error {
+	if o.ValueFile == "" && o.ValueEnv == "" {
+		return nil
+	}
+
+	if o.Value != nil || o.ValueFrom != "" || (o.ValueFile != "" && o.ValueEnv != "") {
+		return errors.New("only one of value, valueFrom, valueFile and valueEnv can be set")
+	}
+
+	var bs []byte
+	if o.ValueFile != "" {
+		path := o.ValueFile
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+
+		var err error
+		if vl, ok := loader.(ValueLoader); ok {
+			bs, err = vl.ReadValueFile(path)
+		} else {
+			bs, err = loader.ReadFile(path)
+		}
+		if err != nil {
+			return fmt.Errorf("could not read valueFile: %s", err)
+		}
+	} else {
+		env, ok := loader.LookupEnv(o.ValueEnv)
+		if !ok {
+			return fmt.Errorf("environment variable is not set: %s", o.ValueEnv)
+		}
+
+		bs = []byte(env)
+	}
+
+	switch o.Decoding {
+	case "":
+	case encodingBase64:
+		decoded, err := base64.StdEncoding.DecodeString(string(bs))
+		if err != nil {
+			return fmt.Errorf("could not decode value: %s", err)
+		}
+		bs = decoded
+	default:
+		return fmt.Errorf("Unexpected decoding: %s", o.Decoding)
+	}
+
+	var val interface{}
+	switch o.Encoding {
+	case "":
+		if o.Raw {
+			val = string(bs)
+		} else if err := yaml.Unmarshal(bs, &val); err != nil {
+			return fmt.Errorf("could not unmarshal value: %s", err)
+		}
+	case encodingBase64:
+		val = base64.StdEncoding.EncodeToString(bs)
+	default:
+		return fmt.Errorf("Unexpected encoding: %s", o.Encoding)
+	}
+
+	o.Value = NewNode(&val)
+	o.ValueFile = ""
+	o.ValueEnv = ""
+	o.Raw = false
+	o.Encoding = ""
+	o.Decoding = ""
+
+	return nil
+}
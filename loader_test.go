@@ -0,0 +1,348 @@
+package yamlpatch_test
+
+import (
+	"os"
+
+	yamlpatch "github.com/krishicks/yaml-patch"
+	yaml "gopkg.in/yaml.v2"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+type memLoader struct {
+	files map[string]string
+	env   map[string]string
+}
+
+func (l memLoader) ReadFile(path string) ([]byte, error) {
+	contents, ok := l.files[path]
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+
+	return []byte(contents), nil
+}
+
+func (l memLoader) LookupEnv(name string) (string, bool) {
+	v, ok := l.env[name]
+	return v, ok
+}
+
+// valueLoader is a memLoader that reads value files from their own files
+type valueLoader struct {
+	memLoader
+	values map[string]string
+}
+
+func (l valueLoader) ReadValueFile(path string) ([]byte, error) {
+	contents, ok := l.values[path]
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+
+	return []byte(contents), nil
+}
+
+var _ = Describe("DecodePatchFile", func() {
+	var loader memLoader
+
+	BeforeEach(func() {
+		loader = memLoader{
+			files: map[string]string{
+				"/ops/values/config.yml": "port: 8080\nhosts: [a, b]\n",
+				"/ops/values/script.sh":  "#!/bin/sh\necho hi\n",
+				"/ops/values/cert.b64":   "Y2VydA==",
+			},
+			env: map[string]string{
+				"REPLICAS": "3",
+				"TOKEN":    "c2VjcmV0",
+			},
+		}
+	})
+
+	DescribeTable(
+		"loading values",
+		func(ops, doc, expectedYAML string) {
+			loader.files["/ops/ops.yml"] = ops
+
+			patch, err := yamlpatch.DecodePatchFile(loader, "/ops/ops.yml")
+			Expect(err).NotTo(HaveOccurred())
+
+			actualBytes, err := patch.Apply([]byte(doc))
+			Expect(err).NotTo(HaveOccurred())
+
+			var actualIface interface{}
+			err = yaml.Unmarshal(actualBytes, &actualIface)
+			Expect(err).NotTo(HaveOccurred())
+
+			var expectedIface interface{}
+			err = yaml.Unmarshal([]byte(expectedYAML), &expectedIface)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(actualIface).To(Equal(expectedIface))
+		},
+		Entry("from a YAML file relative to the ops file",
+			`---
+- op: add
+  path: /config
+  valueFile: values/config.yml
+`,
+			`---
+foo: bar
+`,
+			`---
+foo: bar
+config:
+  port: 8080
+  hosts: [a, b]
+`,
+		),
+		Entry("from a raw file",
+			`---
+- op: add
+  path: /script
+  valueFile: /ops/values/script.sh
+  raw: true
+`,
+			`---
+foo: bar
+`,
+			`---
+foo: bar
+script: |
+  #!/bin/sh
+  echo hi
+`,
+		),
+		Entry("from a base64 encoded file",
+			`---
+- op: add
+  path: /cert
+  valueFile: values/cert.b64
+  decoding: base64
+  raw: true
+`,
+			`---
+foo: bar
+`,
+			`---
+foo: bar
+cert: cert
+`,
+		),
+		Entry("from a file that is base64 encoded",
+			`---
+- op: add
+  path: /script
+  valueFile: values/script.sh
+  encoding: base64
+`,
+			`---
+foo: bar
+`,
+			`---
+foo: bar
+script: IyEvYmluL3NoCmVjaG8gaGkK
+`,
+		),
+		Entry("from environment variables",
+			`---
+- op: replace
+  path: /replicas
+  valueEnv: REPLICAS
+- op: add
+  path: /token
+  valueEnv: TOKEN
+  decoding: base64
+  raw: true
+`,
+			`---
+replicas: 1
+`,
+			`---
+replicas: 3
+token: secret
+`,
+		),
+		Entry("in an operation nested in a foreach",
+			`---
+- op: foreach
+  path: /jobs/name=web
+  ops:
+  - op: add
+    path: /config
+    valueFile: values/config.yml
+`,
+			`---
+jobs:
+- name: web
+`,
+			`---
+jobs:
+- name: web
+  config:
+    port: 8080
+    hosts: [a, b]
+`,
+		),
+	)
+
+	DescribeTable(
+		"failing to load values",
+		func(ops string) {
+			loader.files["/ops/ops.yml"] = ops
+
+			_, err := yamlpatch.DecodePatchFile(loader, "/ops/ops.yml")
+			Expect(err).To(HaveOccurred())
+		},
+		Entry("from a nonexistent file",
+			`---
+- op: add
+  path: /foo
+  valueFile: missing.yml
+`,
+		),
+		Entry("from an unset environment variable",
+			`---
+- op: add
+  path: /foo
+  valueEnv: MISSING
+`,
+		),
+		Entry("from both a file and a value",
+			`---
+- op: add
+  path: /foo
+  value: bar
+  valueFile: values/config.yml
+`,
+		),
+		Entry("with invalid base64",
+			`---
+- op: add
+  path: /foo
+  valueFile: values/script.sh
+  decoding: base64
+`,
+		),
+		Entry("with an unknown encoding",
+			`---
+- op: add
+  path: /foo
+  valueFile: values/script.sh
+  encoding: hex
+`,
+		),
+	)
+
+	It("reads value files with a ValueLoader when the loader is one", func() {
+		loader.files["/ops/ops.yml"] = `---
+- op: add
+  path: /script
+  valueFile: values/script.sh
+  raw: true
+`
+		vl := valueLoader{memLoader: loader, values: map[string]string{"/ops/values/script.sh": "echo {{ x }}"}}
+
+		patch, err := yamlpatch.DecodePatchFile(vl, "/ops/ops.yml")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(patch[0].Value.Value()).To(Equal("echo {{ x }}"))
+	})
+
+	It("returns an error when the ops file does not exist", func() {
+		_, err := yamlpatch.DecodePatchFile(loader, "/ops/missing.yml")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("fails to apply values that were not loaded", func() {
+		patch, err := yamlpatch.DecodePatch([]byte(`---
+- op: add
+  path: /foo
+  valueFile: values/config.yml
+`))
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = patch.Apply([]byte("bar: baz\n"))
+		Expect(err).To(HaveOccurred())
+	})
+
+	Describe("includes", func() {
+		BeforeEach(func() {
+			loader.files["/ops/ops.yml"] = `---
+- op: add
+  path: /foo
+  value: bar
+- include: common/scale.yml
+  vars:
+    instances: 3
+- op: add
+  path: /baz
+  value: qux
+`
+			loader.files["/ops/common/scale.yml"] = `---
+- op: replace
+  path: /instances
+  value: ((instances))
+- include: ../values/labels.yml
+`
+			loader.files["/ops/values/labels.yml"] = `---
+- op: add
+  path: /labels
+  valueFile: config.yml
+  raw: true
+`
+			loader.files["/ops/values/config.yml"] = "((unset))"
+		})
+
+		It("expands included ops files in place, recursively", func() {
+			patch, err := yamlpatch.DecodePatchFile(loader, "/ops/ops.yml")
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(patch).To(HaveLen(4))
+			Expect(patch[0].Path).To(Equal(yamlpatch.OpPath("/foo")))
+			Expect(patch[1].Path).To(Equal(yamlpatch.OpPath("/instances")))
+			Expect(patch[1].Value.Value()).To(Equal(3))
+			Expect(patch[2].Path).To(Equal(yamlpatch.OpPath("/labels")))
+			Expect(patch[2].Value.Value()).To(Equal("((unset))"))
+			Expect(patch[3].Path).To(Equal(yamlpatch.OpPath("/baz")))
+		})
+
+		It("returns an error showing the include chain", func() {
+			loader.files["/ops/values/labels.yml"] = `---
+- op: add
+  path: /labels
+  valueEnv: MISSING
+`
+
+			_, err := yamlpatch.DecodePatchFile(loader, "/ops/ops.yml")
+			Expect(err).To(MatchError("/ops/ops.yml -> /ops/common/scale.yml -> /ops/values/labels.yml: operation 0: environment variable is not set: MISSING"))
+		})
+
+		It("returns an error when includes form a cycle", func() {
+			loader.files["/ops/values/labels.yml"] = `---
+- include: ../ops.yml
+`
+
+			_, err := yamlpatch.DecodePatchFile(loader, "/ops/ops.yml")
+			Expect(err).To(MatchError("include cycle: /ops/ops.yml -> /ops/common/scale.yml -> /ops/values/labels.yml -> /ops/ops.yml"))
+		})
+
+		It("returns an error when an included file does not exist", func() {
+			delete(loader.files, "/ops/common/scale.yml")
+
+			_, err := yamlpatch.DecodePatchFile(loader, "/ops/ops.yml")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(HavePrefix("/ops/ops.yml -> /ops/common/scale.yml: "))
+		})
+
+		It("fails to apply includes that were not expanded", func() {
+			patch, err := yamlpatch.DecodePatch([]byte(loader.files["/ops/ops.yml"]))
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = patch.Apply([]byte("instances: 1\n"))
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
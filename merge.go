@@ -0,0 +1,141 @@
+package yamlpatch
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// ListStrategy determines how lists are combined when deep-merging
+type ListStrategy string
+
+// List strategies
+const (
+	ListReplace    ListStrategy = "replace"
+	ListAppend     ListStrategy = "append"
+	ListPrepend    ListStrategy = "prepend"
+	ListMergeByKey ListStrategy = "merge"
+)
+
+// MergeOptions configures a deep merge
+type MergeOptions struct {
+	// Strategy is used when both sides of the merge are lists. It defaults
+	// to ListReplace.
+	Strategy ListStrategy
+	// Key is the key used to match elements of two lists when Strategy is
+	// ListMergeByKey, e.g. "name"
+	Key string
+}
+
+// Merge deep-merges val into the node held by the container at key. Maps are
+// merged recursively, lists are combined according to opts.Strategy, and any
+// other value replaces the existing one. If the key does not exist, val is
+// added as-is.
+func Merge(c Container, key string, val *Node, opts MergeOptions) error {
+	switch opts.Strategy {
+	case "", ListReplace, ListAppend, ListPrepend:
+	case ListMergeByKey:
+		if opts.Key == "" {
+			return fmt.Errorf("list strategy %s requires a key", opts.Strategy)
+		}
+	default:
+		return fmt.Errorf("Unexpected list strategy: %s", opts.Strategy)
+	}
+
+	existing, err := c.Get(key)
+	if err != nil || existing == nil {
+		return c.Add(key, val)
+	}
+
+	return c.Set(key, mergeNodes(existing, val, opts))
+}
+
+func mergeNodes(dst, src *Node, opts MergeOptions) *Node {
+	if dst == nil || dst.Empty() || src == nil || src.Empty() {
+		return src
+	}
+
+	switch sc := src.Container().(type) {
+	case *nodeMap:
+		dc, ok := dst.Container().(*nodeMap)
+		if !ok {
+			return src
+		}
+
+		for k, v := range *sc {
+			if existing, ok := (*dc)[k]; ok {
+				(*dc)[k] = mergeNodes(existing, v, opts)
+			} else {
+				(*dc)[k] = v
+			}
+		}
+
+		return dst
+	case *nodeSlice:
+		dc, ok := dst.Container().(*nodeSlice)
+		if !ok {
+			return src
+		}
+
+		switch opts.Strategy {
+		case ListAppend:
+			*dc = append(*dc, *sc...)
+		case ListPrepend:
+			ary := make(nodeSlice, 0, len(*sc)+len(*dc))
+			ary = append(ary, *sc...)
+			*dc = append(ary, *dc...)
+		case ListMergeByKey:
+			mergeByKey(dc, sc, opts)
+		default:
+			return src
+		}
+
+		return dst
+	}
+
+	return src
+}
+
+func mergeByKey(dst, src *nodeSlice, opts MergeOptions) {
+	for _, v := range *src {
+		i := indexByKey(*dst, opts.Key, keyValue(v, opts.Key))
+		if i < 0 {
+			*dst = append(*dst, v)
+			continue
+		}
+
+		(*dst)[i] = mergeNodes((*dst)[i], v, opts)
+	}
+}
+
+// keyValue returns the value found at key when the node is a map, or nil
+func keyValue(n *Node, key string) interface{} {
+	if n == nil || n.Empty() {
+		return nil
+	}
+
+	m, ok := n.Container().(*nodeMap)
+	if !ok {
+		return nil
+	}
+
+	v, _ := m.Get(key)
+	if v == nil {
+		return nil
+	}
+
+	return v.Value()
+}
+
+func indexByKey(ary nodeSlice, key string, val interface{}) int {
+	if val == nil {
+		return -1
+	}
+
+	for i, n := range ary {
+		if reflect.DeepEqual(keyValue(n, key), val) {
+			return i
+		}
+	}
+
+	return -1
+}
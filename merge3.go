@@ -0,0 +1,343 @@
+package yamlpatch
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Merge3Strategy decides a conflict in a three-way merge
+type Merge3Strategy string
+
+// Three-way merge strategies
+const (
+	// Merge3Ours takes our side of each conflict
+	Merge3Ours Merge3Strategy = "ours"
+	// Merge3Theirs takes their side of each conflict
+	Merge3Theirs Merge3Strategy = "theirs"
+)
+
+// Merge3Options configures a three-way merge
+type Merge3Options struct {
+	// Strategy, if set, decides each conflict in favor of one side. The
+	// conflicts are still returned.
+	Strategy Merge3Strategy
+	// Markers writes both sides of each conflict undecided by Strategy into
+	// the result between conflict markers, as git does. Otherwise our side
+	// is kept.
+	Markers bool
+}
+
+// Merge3Conflict is a path that both sides of a three-way merge changed
+// differently
+type Merge3Conflict struct {
+	Path OpPath
+	// Base, Ours and Theirs are the values at the path on each side, or nil
+	// where the path does not exist
+	Base, Ours, Theirs *Node
+}
+
+func (c Merge3Conflict) String() string {
+	path := c.Path
+	if path == "" {
+		path = "/"
+	}
+
+	return fmt.Sprintf("conflict at %s: ours %s, theirs %s", path, describeChange(c.Base, c.Ours), describeChange(c.Base, c.Theirs))
+}
+
+func describeChange(base, n *Node) string {
+	switch {
+	case n == nil:
+		return "removed it"
+	case base == nil:
+		return "added it"
+	}
+
+	return "changed it"
+}
+
+const (
+	oursMarker   = "<<<<<<< ours"
+	sepMarker    = "======="
+	theirsMarker = ">>>>>>> theirs"
+)
+
+// Merge3 merges the changes made to base by ours and theirs. Changes made by
+// only one side are taken from that side, and maps, and arrays whose length is
+// unchanged, are merged by key or index. Any path changed differently by each
+// side is a conflict, for which our side is kept.
+func Merge3(base, ours, theirs []byte) ([]byte, []Merge3Conflict, error) {
+	return Merge3WithOptions(base, ours, theirs, Merge3Options{})
+}
+
+// Merge3WithOptions is like Merge3, but decides or marks conflicts according
+// to the given options
+func Merge3WithOptions(base, ours, theirs []byte, opts Merge3Options) ([]byte, []Merge3Conflict, error) {
+	switch opts.Strategy {
+	case "", Merge3Ours, Merge3Theirs:
+	default:
+		return nil, nil, fmt.Errorf("Unexpected merge strategy: %s", opts.Strategy)
+	}
+
+	nodes := make([]*Node, 3)
+	for i, doc := range [][]byte{base, ours, theirs} {
+		var iface interface{}
+		err := yaml.Unmarshal(doc, &iface)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed unmarshaling doc: %s\n\n%s", string(doc), err)
+		}
+		nodes[i] = NewNode(&iface)
+	}
+
+	m := &merger{opts: opts}
+	merged := m.merge(nodes[0], nodes[1], nodes[2], mergeSite{})
+
+	var val interface{}
+	if merged != nil {
+		val = merged.Value()
+	}
+
+	bs, err := yaml.Marshal(val)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(m.marked) > 0 {
+		bs, err = m.writeMarkers(bs)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return bs, m.conflicts, nil
+}
+
+// mergeSite is where a value being merged is found
+type mergeSite struct {
+	path OpPath
+	// key is the key of the value within a map, unless it is within an array
+	key     interface{}
+	inArray bool
+}
+
+// markedConflict is a conflict to be written between conflict markers
+type markedConflict struct {
+	placeholder string
+	site        mergeSite
+	ours        *Node
+	theirs      *Node
+}
+
+type merger struct {
+	opts      Merge3Options
+	conflicts []Merge3Conflict
+	marked    []markedConflict
+}
+
+// merge returns the result of merging the two sides, or nil if the value
+// should not exist
+func (m *merger) merge(base, ours, theirs *Node, site mergeSite) *Node {
+	switch {
+	case sameNode(ours, theirs):
+		return ours
+	case sameNode(base, ours):
+		return theirs
+	case sameNode(base, theirs):
+		return ours
+	}
+
+	if ours != nil && theirs != nil {
+		switch oc := ours.Container().(type) {
+		case *nodeMap:
+			tc, ok := theirs.Container().(*nodeMap)
+			if !ok {
+				break
+			}
+
+			var bc *nodeMap
+			if base != nil {
+				if bc, ok = base.Container().(*nodeMap); !ok {
+					break
+				}
+			}
+
+			return m.mergeMaps(bc, oc, tc, site.path)
+		case *nodeSlice:
+			tc, ok := theirs.Container().(*nodeSlice)
+			if !ok || base == nil {
+				break
+			}
+
+			bc, ok := base.Container().(*nodeSlice)
+			if !ok || len(*bc) != len(*oc) || len(*oc) != len(*tc) {
+				break
+			}
+
+			return m.mergeSlices(bc, oc, tc, site.path)
+		}
+	}
+
+	return m.conflict(base, ours, theirs, site)
+}
+
+func (m *merger) mergeMaps(base, ours, theirs *nodeMap, path OpPath) *Node {
+	keys := map[string]interface{}{}
+	for _, c := range []*nodeMap{base, ours, theirs} {
+		if c == nil {
+			continue
+		}
+		for k := range *c {
+			keys[fmt.Sprint(k)] = k
+		}
+	}
+
+	var names []string
+	for name := range keys {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	get := func(c *nodeMap, k interface{}) *Node {
+		if c == nil {
+			return nil
+		}
+		return (*c)[k]
+	}
+
+	merged := make(map[interface{}]interface{}, len(keys))
+	for _, name := range names {
+		k := keys[name]
+		site := mergeSite{path: OpPath(fmt.Sprintf("%s/%s", path, encodePatchKey(name))), key: k}
+
+		n := m.merge(get(base, k), get(ours, k), get(theirs, k), site)
+		if n != nil {
+			merged[k] = n.Value()
+		}
+	}
+
+	var val interface{} = merged
+	return NewNode(&val)
+}
+
+func (m *merger) mergeSlices(base, ours, theirs *nodeSlice, path OpPath) *Node {
+	merged := make([]interface{}, len(*ours))
+	for i := range *ours {
+		site := mergeSite{path: OpPath(fmt.Sprintf("%s/%d", path, i)), inArray: true}
+
+		n := m.merge((*base)[i], (*ours)[i], (*theirs)[i], site)
+		if n != nil {
+			merged[i] = n.Value()
+		}
+	}
+
+	var val interface{} = merged
+	return NewNode(&val)
+}
+
+// conflict records a conflict and returns the value to keep for it
+func (m *merger) conflict(base, ours, theirs *Node, site mergeSite) *Node {
+	m.conflicts = append(m.conflicts, Merge3Conflict{Path: site.path, Base: base, Ours: ours, Theirs: theirs})
+
+	switch {
+	case m.opts.Strategy == Merge3Theirs:
+		return theirs
+	case m.opts.Strategy == Merge3Ours || !m.opts.Markers:
+		return ours
+	}
+
+	var placeholder interface{} = fmt.Sprintf("__yaml_patch_conflict_%d__", len(m.marked))
+	m.marked = append(m.marked, markedConflict{
+		placeholder: placeholder.(string),
+		site:        site,
+		ours:        ours,
+		theirs:      theirs,
+	})
+
+	return NewNode(&placeholder)
+}
+
+// writeMarkers replaces the line holding each conflict's placeholder with
+// both sides of the conflict between conflict markers
+func (m *merger) writeMarkers(doc []byte) ([]byte, error) {
+	lines := strings.Split(string(doc), "\n")
+
+	for _, c := range m.marked {
+		for i, line := range lines {
+			at := strings.Index(line, c.placeholder)
+			if at < 0 {
+				continue
+			}
+
+			// the indentation, along with the dashes of any array items
+			// that the value starts
+			leading := line[:len(line)-len(strings.TrimLeft(line, " -"))]
+
+			ours, err := markedSide(c.site, c.ours, leading)
+			if err != nil {
+				return nil, err
+			}
+
+			theirs, err := markedSide(c.site, c.theirs, leading)
+			if err != nil {
+				return nil, err
+			}
+
+			var block []string
+			block = append(block, oursMarker)
+			block = append(block, ours...)
+			block = append(block, sepMarker)
+			block = append(block, theirs...)
+			block = append(block, theirsMarker)
+
+			lines = append(lines[:i], append(block, lines[i+1:]...)...)
+			break
+		}
+	}
+
+	return []byte(strings.Join(lines, "\n")), nil
+}
+
+// markedSide returns the lines for one side of a conflict, as they would
+// appear at the conflict's site following the leading indentation
+func markedSide(site mergeSite, n *Node, leading string) ([]string, error) {
+	if n == nil {
+		return nil, nil
+	}
+
+	var v interface{} = n.Value()
+	if site.path != "" && !site.inArray {
+		v = yaml.MapSlice{{Key: site.key, Value: v}}
+	}
+
+	bs, err := yaml.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	indent := strings.Repeat(" ", len(leading))
+
+	lines := strings.Split(string(bytes.TrimSuffix(bs, []byte("\n"))), "\n")
+	for i := range lines {
+		if i == 0 {
+			lines[i] = leading + lines[i]
+		} else {
+			lines[i] = indent + lines[i]
+		}
+	}
+
+	return lines, nil
+}
+
+// sameNode returns whether both nodes are absent, or both are present with
+// equal values
+func sameNode(a, b *Node) bool {
+	if (a == nil) != (b == nil) {
+		return false
+	}
+
+	return a.Equal(b)
+}
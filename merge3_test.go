@@ -0,0 +1,239 @@
+package yamlpatch_test
+
+import (
+	yamlpatch "github.com/krishicks/yaml-patch"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Merge3", func() {
+	base := []byte(`---
+name: web
+instances: 1
+labels:
+  team: core
+jobs:
+- name: job1
+  serial: false
+- name: job2
+  serial: false
+`)
+
+	It("takes the changes made by each side", func() {
+		merged, conflicts, err := yamlpatch.Merge3(base, []byte(`---
+name: web
+instances: 3
+labels:
+  team: core
+  tier: frontend
+jobs:
+- name: job1
+  serial: true
+- name: job2
+  serial: false
+`), []byte(`---
+name: web-app
+instances: 1
+labels:
+  team: core
+jobs:
+- name: job1
+  serial: false
+- name: job2
+  serial: true
+`))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(conflicts).To(BeEmpty())
+
+		Expect(merged).To(MatchYAML(`---
+name: web-app
+instances: 3
+labels:
+  team: core
+  tier: frontend
+jobs:
+- name: job1
+  serial: true
+- name: job2
+  serial: true
+`))
+	})
+
+	It("takes removals and array changes made by one side", func() {
+		merged, conflicts, err := yamlpatch.Merge3(base, []byte(`---
+name: web
+instances: 1
+jobs:
+- name: job1
+  serial: false
+- name: job2
+  serial: false
+`), []byte(`---
+name: web
+instances: 1
+labels:
+  team: core
+jobs:
+- name: job1
+  serial: false
+- name: job2
+  serial: false
+- name: job3
+`))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(conflicts).To(BeEmpty())
+
+		Expect(merged).To(MatchYAML(`---
+name: web
+instances: 1
+jobs:
+- name: job1
+  serial: false
+- name: job2
+  serial: false
+- name: job3
+`))
+	})
+
+	Context("when both sides change the same path differently", func() {
+		ours := []byte(`---
+name: web
+instances: 3
+labels:
+  team: core
+jobs:
+- name: job1
+  serial: false
+- name: job2
+  serial: false
+`)
+		theirs := []byte(`---
+name: web
+instances: 5
+jobs:
+- name: job1
+  serial: false
+- name: job2
+  serial: false
+`)
+		oursRemoves := []byte(`---
+name: web
+instances: 1
+labels:
+  team: platform
+jobs:
+- name: job1
+  serial: false
+- name: job2
+  serial: false
+`)
+
+		It("reports a conflict for each path, keeping our side", func() {
+			merged, conflicts, err := yamlpatch.Merge3(base, ours, theirs)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(conflicts).To(HaveLen(1))
+			Expect(conflicts[0].Path).To(Equal(yamlpatch.OpPath("/instances")))
+			Expect(conflicts[0].Base.Value()).To(Equal(1))
+			Expect(conflicts[0].Ours.Value()).To(Equal(3))
+			Expect(conflicts[0].Theirs.Value()).To(Equal(5))
+			Expect(conflicts[0].String()).To(Equal("conflict at /instances: ours changed it, theirs changed it"))
+
+			Expect(merged).To(MatchYAML(`---
+name: web
+instances: 3
+jobs:
+- name: job1
+  serial: false
+- name: job2
+  serial: false
+`))
+		})
+
+		It("takes their side when using the theirs strategy", func() {
+			merged, conflicts, err := yamlpatch.Merge3WithOptions(base, oursRemoves, theirs, yamlpatch.Merge3Options{
+				Strategy: yamlpatch.Merge3Theirs,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(conflicts).To(HaveLen(1))
+			Expect(conflicts[0].Path).To(Equal(yamlpatch.OpPath("/labels")))
+			Expect(conflicts[0].Theirs).To(BeNil())
+			Expect(conflicts[0].String()).To(Equal("conflict at /labels: ours changed it, theirs removed it"))
+
+			Expect(merged).To(MatchYAML(`---
+name: web
+instances: 5
+jobs:
+- name: job1
+  serial: false
+- name: job2
+  serial: false
+`))
+		})
+
+		It("writes both sides between conflict markers", func() {
+			merged, conflicts, err := yamlpatch.Merge3WithOptions(base, oursRemoves, []byte(`---
+name: web
+instances: 1
+labels:
+  team: core
+jobs:
+- name: job1
+  serial: false
+- name: job2
+  serial: true
+  plan: [get: A]
+`), yamlpatch.Merge3Options{Markers: true})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(conflicts).To(BeEmpty())
+			Expect(merged).NotTo(ContainSubstring("<<<<<<<"))
+
+			merged, conflicts, err = yamlpatch.Merge3WithOptions(base, []byte(`---
+name: web
+instances: 1
+labels:
+  team: platform
+jobs:
+- name: deploy
+  serial: false
+- name: job2
+  serial: false
+`), []byte(`---
+name: web
+instances: 1
+jobs:
+- name: build
+  serial: false
+- name: job2
+  serial: false
+`), yamlpatch.Merge3Options{Markers: true})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(conflicts).To(HaveLen(2))
+
+			Expect(string(merged)).To(Equal(`instances: 1
+jobs:
+<<<<<<< ours
+- name: deploy
+=======
+- name: build
+>>>>>>> theirs
+  serial: false
+- name: job2
+  serial: false
+<<<<<<< ours
+labels:
+  team: platform
+=======
+>>>>>>> theirs
+name: web
+`))
+		})
+	})
+
+	It("returns an error for an unknown strategy", func() {
+		_, _, err := yamlpatch.Merge3WithOptions(base, base, base, yamlpatch.Merge3Options{Strategy: "both"})
+		Expect(err).To(MatchError("Unexpected merge strategy: both"))
+	})
+})
@@ -72,12 +72,58 @@ func (n *Node) Container() Container {
 }
 
 // Equal compares the values of the raw interfaces that the YAML was
-// unmarshaled into
+// unmarshaled into, including any changes made through the node's Container.
+// A nil Node is equal to a node with a nil value.
 func (n *Node) Equal(other *Node) bool {
-	return reflect.DeepEqual(*n.raw, *other.raw)
+	return reflect.DeepEqual(n.value(), other.value())
 }
 
-// Value returns the raw value of the node
+// Value returns the raw value of the node, including any changes made through
+// the node's Container
 func (n *Node) Value() interface{} {
+	if v, ok := containerValue(n.container); ok {
+		return v
+	}
+
 	return *n.raw
 }
+
+// containerValue returns the raw value of the container, and whether the
+// container is one whose value is known
+func containerValue(c Container) (interface{}, bool) {
+	switch ct := c.(type) {
+	case *nodeMap:
+		m := make(map[interface{}]interface{}, len(*ct))
+		for k, v := range *ct {
+			m[k] = v.value()
+		}
+		return m, true
+	case *nodeSlice:
+		ary := make([]interface{}, len(*ct))
+		for i, v := range *ct {
+			ary[i] = v.value()
+		}
+		return ary, true
+	}
+
+	return nil, false
+}
+
+// value is like Value, but returns nil for a nil node
+func (n *Node) value() interface{} {
+	if n == nil {
+		return nil
+	}
+
+	return n.Value()
+}
+
+// clone returns a deep copy of the node
+func (n *Node) clone() *Node {
+	if n == nil {
+		return nil
+	}
+
+	val := copyValue(n.Value())
+	return NewNode(&val)
+}
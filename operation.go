@@ -17,6 +17,35 @@ const (
 	opMove    Op = "move"
 	opCopy    Op = "copy"
 	opTest    Op = "test"
+	opMerge   Op = "merge"
+
+	opExtend      Op = "extend"
+	opAddUnique   Op = "add-unique"
+	opRemoveValue Op = "remove-value"
+	opRename      Op = "rename"
+	opSort        Op = "sort"
+	opDedupe      Op = "dedupe"
+	opTransform   Op = "transform"
+	opIncrement   Op = "increment"
+	opDecrement   Op = "decrement"
+	opMultiply    Op = "multiply"
+	opMin         Op = "min"
+	opMax         Op = "max"
+	opForeach     Op = "foreach"
+
+	opTestAbsent   Op = "test-absent"
+	opTestPresent  Op = "test-present"
+	opTestType     Op = "test-type"
+	opTestRegex    Op = "test-regex"
+	opTestRange    Op = "test-range"
+	opTestLength   Op = "test-length"
+	opTestContains Op = "test-contains"
+)
+
+// Sort orders
+const (
+	orderAscending  = "asc"
+	orderDescending = "desc"
 )
 
 // OpPath is an RFC6902 'pointer'
@@ -57,27 +86,146 @@ type Operation struct {
 	Path  OpPath `yaml:"path,omitempty"`
 	From  OpPath `yaml:"from,omitempty"`
 	Value *Node  `yaml:"value,omitempty"`
+
+	// ValueFrom is a pointer to a node in the document whose value is used
+	// as the operation's value
+	ValueFrom OpPath `yaml:"valueFrom,omitempty"`
+
+	// ResolveRefs replaces each { $ref: <pointer> } map embedded in Value
+	// with the value at the pointer. Without it such maps are written as-is.
+	ResolveRefs bool `yaml:"resolveRefs,omitempty"`
+
+	// ValueFile and ValueEnv name a file or environment variable that the
+	// value is loaded from by DecodePatchFile. The contents are unmarshaled
+	// as YAML unless Raw is set, and can be base64 encoded or decoded.
+	ValueFile string `yaml:"valueFile,omitempty"`
+	ValueEnv  string `yaml:"valueEnv,omitempty"`
+	Raw       bool   `yaml:"raw,omitempty"`
+	Encoding  string `yaml:"encoding,omitempty"`
+	Decoding  string `yaml:"decoding,omitempty"`
+
+	// Include names an ops file that DecodePatchFile expands in place of the
+	// operation, substituting each ((name)) in it with the value in Vars
+	Include string                 `yaml:"include,omitempty"`
+	Vars    map[string]interface{} `yaml:"vars,omitempty"`
+
+	// Ops are performed by a foreach on each node matched by Path, with paths
+	// relative to that node
+	Ops Patch `yaml:"ops,omitempty"`
+
+	// Strategy and Key configure how lists are combined by a merge. Key is
+	// also the key that a sort or dedupe compares elements by.
+	Strategy ListStrategy `yaml:"strategy,omitempty"`
+	Key      string       `yaml:"key,omitempty"`
+
+	// Order is the order of a sort, either "asc" (the default) or "desc"
+	Order string `yaml:"order,omitempty"`
+
+	// Function and Pattern configure a transform, which uses Value as the
+	// replacement, affix or template. Pattern is also the regular expression
+	// of a test-regex.
+	Function string `yaml:"function,omitempty"`
+	Pattern  string `yaml:"pattern,omitempty"`
+
+	// Min and Max are the inclusive bounds of a test-range or test-length
+	Min *float64 `yaml:"min,omitempty"`
+	Max *float64 `yaml:"max,omitempty"`
+
+	// To and Overwrite configure a rename
+	To        string `yaml:"to,omitempty"`
+	Overwrite bool   `yaml:"overwrite,omitempty"`
+
+	// Comment describes the operation. It is carried into the document when
+	// a patch is applied with Annotate set.
+	Comment string `yaml:"comment,omitempty"`
+
+	// Source is where the operation was decoded from
+	Source Source `yaml:"-"`
+}
+
+// Source is the location of an operation in an ops file
+type Source struct {
+	// File is empty when the operation was not decoded from a file
+	File string
+	// Index is the position of the operation in the file
+	Index  int
+	Line   int
+	Column int
+}
+
+// String returns the source as "file:line", "line N" when the file is not
+// known, or "file#index" when the line is not known
+func (s Source) String() string {
+	switch {
+	case s.Line == 0:
+		return fmt.Sprintf("%s#%d", s.File, s.Index)
+	case s.File == "":
+		return fmt.Sprintf("line %d", s.Line)
+	}
+
+	return fmt.Sprintf("%s:%d", s.File, s.Line)
 }
 
 // Perform executes the operation on the given container
 func (o *Operation) Perform(c Container) error {
-	var err error
+	if o.Include != "" {
+		return fmt.Errorf("yamlpatch include of %s must be expanded by DecodePatchFile", o.Include)
+	}
+
+	op, err := o.resolveValue(c)
+	if err != nil {
+		return fmt.Errorf("yamlpatch %s operation does not apply: %s", o.Op, err)
+	}
 
-	switch o.Op {
+	switch op.Op {
 	case opAdd:
-		err = tryAdd(c, o)
+		err = tryAdd(c, op)
 	case opRemove:
-		err = tryRemove(c, o)
+		err = tryRemove(c, op)
 	case opReplace:
-		err = tryReplace(c, o)
+		err = tryReplace(c, op)
 	case opMove:
-		err = tryMove(c, o)
+		err = tryMove(c, op)
 	case opCopy:
-		err = tryCopy(c, o)
+		err = tryCopy(c, op)
 	case opTest:
-		err = tryTest(c, o)
+		err = tryTest(c, op)
+	case opMerge:
+		err = tryMerge(c, op)
+	case opExtend:
+		err = tryExtend(c, op)
+	case opAddUnique:
+		err = tryAddUnique(c, op)
+	case opRemoveValue:
+		err = tryRemoveValue(c, op)
+	case opRename:
+		err = tryRename(c, op)
+	case opSort:
+		err = trySort(c, op)
+	case opDedupe:
+		err = tryDedupe(c, op)
+	case opTransform:
+		err = tryTransform(c, op)
+	case opIncrement, opDecrement, opMultiply, opMin, opMax:
+		err = tryArithmetic(c, op)
+	case opForeach:
+		err = tryForeach(c, op)
+	case opTestAbsent:
+		err = tryTestAbsent(c, op)
+	case opTestPresent:
+		err = tryTestPresent(c, op)
+	case opTestType:
+		err = tryTestType(c, op)
+	case opTestRegex:
+		err = tryTestRegex(c, op)
+	case opTestRange:
+		err = tryTestRange(c, op)
+	case opTestLength:
+		err = tryTestLength(c, op)
+	case opTestContains:
+		err = tryTestContains(c, op)
 	default:
-		err = fmt.Errorf("Unexpected op: %s", o.Op)
+		err = fmt.Errorf("Unexpected op: %s", op.Op)
 	}
 
 	return err
@@ -86,7 +234,7 @@ func (o *Operation) Perform(c Container) error {
 func tryAdd(doc Container, op *Operation) error {
 	con, key, err := findContainer(doc, &op.Path)
 	if err != nil {
-		return fmt.Errorf("yamlpatch add operation does not apply: doc is missing path: %s", op.Path)
+		return fmt.Errorf("yamlpatch add operation does not apply: doc is missing path: %s: %s", op.Path, err)
 	}
 
 	return con.Add(key, op.Value)
@@ -95,7 +243,7 @@ func tryAdd(doc Container, op *Operation) error {
 func tryRemove(doc Container, op *Operation) error {
 	con, key, err := findContainer(doc, &op.Path)
 	if err != nil {
-		return fmt.Errorf("yamlpatch remove operation does not apply: doc is missing path: %s", op.Path)
+		return fmt.Errorf("yamlpatch remove operation does not apply: doc is missing path: %s: %s", op.Path, err)
 	}
 
 	return con.Remove(key)
@@ -104,21 +252,225 @@ func tryRemove(doc Container, op *Operation) error {
 func tryReplace(doc Container, op *Operation) error {
 	con, key, err := findContainer(doc, &op.Path)
 	if err != nil {
-		return fmt.Errorf("yamlpatch replace operation does not apply: doc is missing path: %s", op.Path)
+		return fmt.Errorf("yamlpatch replace operation does not apply: doc is missing path: %s: %s", op.Path, err)
 	}
 
 	val, err := con.Get(key)
 	if val == nil || err != nil {
-		return fmt.Errorf("yamlpatch replace operation does not apply: doc is missing key: %s", op.Path)
+		return fmt.Errorf("yamlpatch replace operation does not apply: doc is missing key: %s: %s", op.Path, missingKey(con, parentPath(op.Path), key))
 	}
 
 	return con.Set(key, op.Value)
 }
 
+func tryMerge(doc Container, op *Operation) error {
+	con, key, err := findContainer(doc, &op.Path)
+	if err != nil {
+		return fmt.Errorf("yamlpatch merge operation does not apply: doc is missing path: %s: %s", op.Path, err)
+	}
+
+	return Merge(con, key, op.Value, MergeOptions{
+		Strategy: op.Strategy,
+		Key:      op.Key,
+	})
+}
+
+func tryExtend(doc Container, op *Operation) error {
+	ary, err := findSlice(doc, &op.Path)
+	if err != nil {
+		return fmt.Errorf("yamlpatch extend operation does not apply: %s", err)
+	}
+
+	if op.Value == nil {
+		return fmt.Errorf("yamlpatch extend operation does not apply: value is not an array")
+	}
+
+	vals, ok := op.Value.Container().(*nodeSlice)
+	if !ok {
+		return fmt.Errorf("yamlpatch extend operation does not apply: value is not an array")
+	}
+
+	ary.Extend(*vals...)
+	return nil
+}
+
+func tryAddUnique(doc Container, op *Operation) error {
+	ary, err := findSlice(doc, &op.Path)
+	if err != nil {
+		return fmt.Errorf("yamlpatch add-unique operation does not apply: %s", err)
+	}
+
+	if op.Value == nil {
+		return fmt.Errorf("yamlpatch add-unique operation does not apply: missing value")
+	}
+
+	ary.AddUnique(op.Value)
+	return nil
+}
+
+func tryRemoveValue(doc Container, op *Operation) error {
+	ary, err := findSlice(doc, &op.Path)
+	if err != nil {
+		return fmt.Errorf("yamlpatch remove-value operation does not apply: %s", err)
+	}
+
+	if op.Value == nil {
+		return fmt.Errorf("yamlpatch remove-value operation does not apply: missing value")
+	}
+
+	ary.RemoveValue(op.Value)
+	return nil
+}
+
+func tryRename(doc Container, op *Operation) error {
+	con, key, err := findContainer(doc, &op.Path)
+	if err != nil {
+		return fmt.Errorf("yamlpatch rename operation does not apply: doc is missing path: %s: %s", op.Path, err)
+	}
+
+	if op.To == "" {
+		return fmt.Errorf("yamlpatch rename operation does not apply: missing new key for path: %s", op.Path)
+	}
+
+	r, ok := con.(Renamer)
+	if !ok {
+		return fmt.Errorf("yamlpatch rename operation does not apply: path does not point at a key in an object: %s", op.Path)
+	}
+
+	return r.Rename(key, op.To, op.Overwrite)
+}
+
+func trySort(doc Container, op *Operation) error {
+	ary, err := findSlice(doc, &op.Path)
+	if err != nil {
+		return fmt.Errorf("yamlpatch sort operation does not apply: %s", err)
+	}
+
+	switch op.Order {
+	case "", orderAscending:
+		ary.Sort(op.Key, false)
+	case orderDescending:
+		ary.Sort(op.Key, true)
+	default:
+		return fmt.Errorf("yamlpatch sort operation does not apply: unexpected order: %s", op.Order)
+	}
+
+	return nil
+}
+
+func tryDedupe(doc Container, op *Operation) error {
+	ary, err := findSlice(doc, &op.Path)
+	if err != nil {
+		return fmt.Errorf("yamlpatch dedupe operation does not apply: %s", err)
+	}
+
+	ary.Dedupe(op.Key)
+	return nil
+}
+
+func tryTransform(doc Container, op *Operation) error {
+	con, key, err := findContainer(doc, &op.Path)
+	if err != nil {
+		return fmt.Errorf("yamlpatch transform operation does not apply: doc is missing path: %s: %s", op.Path, err)
+	}
+
+	val, err := con.Get(key)
+	if val == nil || err != nil {
+		return fmt.Errorf("yamlpatch transform operation does not apply: doc is missing key: %s: %s", op.Path, missingKey(con, parentPath(op.Path), key))
+	}
+
+	s, ok := val.Value().(string)
+	if !ok {
+		return fmt.Errorf("yamlpatch transform operation does not apply: value at %s is not a string: %v", op.Path, val.Value())
+	}
+
+	var arg string
+	if op.Value != nil && !op.Value.Empty() {
+		switch v := op.Value.Value().(type) {
+		case map[interface{}]interface{}, []interface{}:
+			return fmt.Errorf("yamlpatch transform operation does not apply: value is not a scalar: %v", v)
+		default:
+			arg = fmt.Sprint(v)
+		}
+	}
+
+	s, err = transformString(op.Function, op.Pattern, arg, s)
+	if err != nil {
+		return fmt.Errorf("yamlpatch transform operation does not apply: %s", err)
+	}
+
+	var iface interface{} = s
+	return con.Set(key, NewNode(&iface))
+}
+
+func tryArithmetic(doc Container, op *Operation) error {
+	con, key, err := findContainer(doc, &op.Path)
+	if err != nil {
+		return fmt.Errorf("yamlpatch %s operation does not apply: doc is missing path: %s: %s", op.Op, op.Path, err)
+	}
+
+	val, err := con.Get(key)
+	if val == nil || err != nil {
+		return fmt.Errorf("yamlpatch %s operation does not apply: doc is missing key: %s: %s", op.Op, op.Path, missingKey(con, parentPath(op.Path), key))
+	}
+
+	if _, ok := toFloat(val.Value()); !ok {
+		return fmt.Errorf("yamlpatch %s operation does not apply: value at %s is not numeric: %v", op.Op, op.Path, val.Value())
+	}
+
+	var operand interface{} = 1
+	if op.Value != nil && !op.Value.Empty() {
+		operand = op.Value.Value()
+	} else if op.Op != opIncrement && op.Op != opDecrement {
+		return fmt.Errorf("yamlpatch %s operation does not apply: missing value", op.Op)
+	}
+
+	if _, ok := toFloat(operand); !ok {
+		return fmt.Errorf("yamlpatch %s operation does not apply: value is not numeric: %v", op.Op, operand)
+	}
+
+	result, err := calculate(op.Op, val.Value(), operand)
+	if err != nil {
+		return fmt.Errorf("yamlpatch %s operation does not apply: %s", op.Op, err)
+	}
+
+	return con.Set(key, NewNode(&result))
+}
+
+func tryForeach(doc Container, op *Operation) error {
+	con, key, err := findContainer(doc, &op.Path)
+	if err != nil {
+		return fmt.Errorf("yamlpatch foreach operation does not apply: doc is missing path: %s: %s", op.Path, err)
+	}
+
+	val, err := con.Get(key)
+	if val == nil || err != nil {
+		return fmt.Errorf("yamlpatch foreach operation does not apply: doc is missing key: %s: %s", op.Path, missingKey(con, parentPath(op.Path), key))
+	}
+
+	c := val.Container()
+	if c == nil {
+		return fmt.Errorf("yamlpatch foreach operation does not apply: path does not point at an object or array: %s", op.Path)
+	}
+
+	return op.foreach(c, nil)
+}
+
+// foreach performs the nested operations on c, the container at the
+// operation's path, notifying obs of each of them
+func (o *Operation) foreach(c Container, obs observer) error {
+	err := o.Ops.apply(c, ApplyOptions{}, obs)
+	if err != nil {
+		return fmt.Errorf("yamlpatch foreach operation failed at %s: %s", o.Path, err)
+	}
+
+	return nil
+}
+
 func tryMove(doc Container, op *Operation) error {
 	con, key, err := findContainer(doc, &op.From)
 	if err != nil {
-		return fmt.Errorf("yamlpatch move operation does not apply: doc is missing from path: %s", op.From)
+		return fmt.Errorf("yamlpatch move operation does not apply: doc is missing from path: %s: %s", op.From, err)
 	}
 
 	val, err := con.Get(key)
@@ -126,6 +478,13 @@ func tryMove(doc Container, op *Operation) error {
 		return err
 	}
 
+	// the destination is checked before the value is removed, so that a move
+	// that cannot be performed leaves the document as it was
+	path := op.Path
+	if _, _, err = findContainer(doc, &path); err != nil {
+		return fmt.Errorf("yamlpatch move operation does not apply: doc is missing destination path: %s: %s", op.Path, err)
+	}
+
 	err = con.Remove(key)
 	if err != nil {
 		return err
@@ -133,7 +492,7 @@ func tryMove(doc Container, op *Operation) error {
 
 	con, key, err = findContainer(doc, &op.Path)
 	if err != nil {
-		return fmt.Errorf("yamlpatch move operation does not apply: doc is missing destination path: %s", op.Path)
+		return fmt.Errorf("yamlpatch move operation does not apply: doc is missing destination path: %s: %s", op.Path, err)
 	}
 
 	return con.Set(key, val)
@@ -142,7 +501,7 @@ func tryMove(doc Container, op *Operation) error {
 func tryCopy(doc Container, op *Operation) error {
 	con, key, err := findContainer(doc, &op.From)
 	if err != nil {
-		return fmt.Errorf("copy operation does not apply: doc is missing from path: %s", op.From)
+		return fmt.Errorf("copy operation does not apply: doc is missing from path: %s: %s", op.From, err)
 	}
 
 	val, err := con.Get(key)
@@ -152,16 +511,16 @@ func tryCopy(doc Container, op *Operation) error {
 
 	con, key, err = findContainer(doc, &op.Path)
 	if err != nil {
-		return fmt.Errorf("copy operation does not apply: doc is missing destination path: %s", op.Path)
+		return fmt.Errorf("copy operation does not apply: doc is missing destination path: %s: %s", op.Path, err)
 	}
 
-	return con.Set(key, val)
+	return con.Set(key, val.clone())
 }
 
 func tryTest(doc Container, op *Operation) error {
 	con, key, err := findContainer(doc, &op.Path)
 	if err != nil {
-		return fmt.Errorf("test operation does not apply: doc is missing from path: %s", op.From)
+		return fmt.Errorf("test operation does not apply: doc is missing path: %s: %s", op.Path, err)
 	}
 
 	val, err := con.Get(key)
@@ -169,10 +528,7 @@ func tryTest(doc Container, op *Operation) error {
 		return err
 	}
 
-	if op.Value.Empty() && val == nil {
-		return nil
-	}
-
+	// a nil value matches both a nil node and a missing key
 	if op.Value.Equal(val) {
 		return nil
 	}
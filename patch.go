@@ -2,14 +2,32 @@ package yamlpatch
 
 import (
 	"fmt"
+	"strings"
 
 	yaml "gopkg.in/yaml.v2"
+	yamlv3 "gopkg.in/yaml.v3"
 )
 
 // Patch is an ordered collection of operations.
 type Patch []Operation
 
-// DecodePatch decodes the passed YAML document as if it were an RFC 6902 patch
+// ApplyOptions configures how a patch is applied
+type ApplyOptions struct {
+	// ContinueOnError skips operations that fail instead of stopping at the
+	// first failure. Every failure is returned in an ApplyErrors.
+	ContinueOnError bool
+	// Hooks, if set, are notified of each operation
+	Hooks Hooks
+	// Provenance, if set, is filled in with the source of the operation that
+	// last changed each path
+	Provenance Provenance
+	// Annotate adds a comment to each node written by the patch, giving the
+	// source of the operation that wrote it and the operation's comment
+	Annotate bool
+}
+
+// DecodePatch decodes the passed YAML document as if it were an RFC 6902
+// patch, recording the position of each operation in its Source
 func DecodePatch(bs []byte) (Patch, error) {
 	var p Patch
 
@@ -18,11 +36,73 @@ func DecodePatch(bs []byte) (Patch, error) {
 		return nil, err
 	}
 
+	positions := operationPositions(bs)
+	for i := range p {
+		p[i].Source.Index = i
+		if i < len(positions) {
+			p[i].Source.Line = positions[i].Line
+			p[i].Source.Column = positions[i].Column
+		}
+	}
+
 	return p, nil
 }
 
+// operationPositions returns the node that each operation in the patch starts
+// at, or nil if the positions cannot be determined
+func operationPositions(bs []byte) []*yamlv3.Node {
+	var doc yamlv3.Node
+	err := yamlv3.Unmarshal(bs, &doc)
+	if err != nil || len(doc.Content) == 0 || doc.Content[0].Kind != yamlv3.SequenceNode {
+		return nil
+	}
+
+	return doc.Content[0].Content
+}
+
 // Apply returns a YAML document that has been mutated per the patch
 func (p Patch) Apply(doc []byte) ([]byte, error) {
+	return p.ApplyWithOptions(doc, ApplyOptions{})
+}
+
+// ApplyWithOptions returns a YAML document that has been mutated per the
+// patch, applied according to the given options. When operations fail with
+// ContinueOnError set, the document is returned along with the ApplyErrors.
+func (p Patch) ApplyWithOptions(doc []byte, opts ApplyOptions) ([]byte, error) {
+	return p.applyDoc(doc, opts, nil)
+}
+
+// observer is notified as apply performs each operation
+type observer interface {
+	// expanded is called once for each operation in the patch, after its
+	// paths are expanded or fail to expand
+	expanded(index int, op *Operation, err error)
+	// beforeOp and afterOp are called around each expanded operation. An
+	// error returned by beforeOp fails the operation without performing it.
+	beforeOp(index int, op *Operation, c Container) error
+	afterOp(index int, op *Operation, c Container, err error)
+}
+
+func (p Patch) applyDoc(doc []byte, opts ApplyOptions, o observer) ([]byte, error) {
+	var obs observers
+	if o != nil {
+		obs = append(obs, o)
+	}
+
+	if opts.Hooks != nil {
+		obs = append(obs, &hooksObserver{hooks: opts.Hooks})
+	}
+
+	if opts.Provenance != nil {
+		obs = append(obs, &provenanceObserver{provenance: opts.Provenance})
+	}
+
+	var annotations *provenanceObserver
+	if opts.Annotate {
+		annotations = &provenanceObserver{provenance: Provenance{}, comments: map[Source]string{}}
+		obs = append(obs, annotations)
+	}
+
 	var iface interface{}
 	err := yaml.Unmarshal(doc, &iface)
 	if err != nil {
@@ -32,29 +112,199 @@ func (p Patch) Apply(doc []byte) ([]byte, error) {
 	var c Container
 	c = NewNode(&iface).Container()
 
-	for _, op := range p {
-		pathfinder := NewPathFinder(c)
-		if op.Path.ContainsExtendedSyntax() {
-			paths := pathfinder.Find(string(op.Path))
-			if paths == nil {
-				return nil, fmt.Errorf("could not expand pointer: %s", op.Path)
+	err = p.apply(c, opts, obs)
+	if err != nil {
+		locateErrors(doc, err)
+
+		if _, ok := err.(ApplyErrors); ok {
+			bs, merr := marshalDoc(c, annotations)
+			if merr != nil {
+				return nil, merr
+			}
+
+			return bs, err
+		}
+
+		return nil, err
+	}
+
+	return marshalDoc(c, annotations)
+}
+
+// marshalDoc marshals the container, annotated with the changes recorded by
+// annotations when it is set
+func marshalDoc(c Container, annotations *provenanceObserver) ([]byte, error) {
+	var v interface{} = c
+	if cv, ok := containerValue(c); ok {
+		v = cv
+	}
+
+	bs, err := marshalValue(v)
+	if err != nil {
+		return nil, err
+	}
+
+	if annotations == nil {
+		return bs, nil
+	}
+
+	return annotate(bs, annotations.annotations())
+}
+
+// apply performs each operation on the container in order, expanding any
+// paths that use extended syntax. With ContinueOnError, an operation that
+// fails at any of its paths is undone before the next one is performed.
+func (p Patch) apply(c Container, opts ApplyOptions, obs observer) error {
+	var errs ApplyErrors
+
+	fail := func(i int, op Operation, err error) error {
+		applyErr := &ApplyError{Index: i, Op: op.Op, Path: op.Path, Source: op.Source, Err: err}
+		applyErr.DocPath, applyErr.docValue = closestPath(c, op.Path)
+		if !opts.ContinueOnError {
+			return applyErr
+		}
+
+		errs = append(errs, applyErr)
+		return nil
+	}
+
+	for i, op := range p {
+		ops, err := op.expand(c)
+		if obs != nil {
+			obs.expanded(i, &op, err)
+		}
+
+		if err != nil {
+			if err = fail(i, op, err); err != nil {
+				return err
+			}
+			continue
+		}
+
+		var snapshot interface{}
+		if opts.ContinueOnError {
+			snapshot, _ = containerValue(c)
+			snapshot = copyValue(snapshot)
+		}
+
+		for _, concrete := range ops {
+			var nested *Node
+			if concrete.Op == opForeach && obs != nil {
+				nested = lookupNode(c, concrete.Path)
 			}
 
-			for _, path := range paths {
-				newOp := op
-				newOp.Path = OpPath(path)
-				err = newOp.Perform(c)
-				if err != nil {
-					return nil, err
+			if nested != nil && nested.Container() != nil {
+				// the nested operations are observed in place of the foreach
+				err = concrete.foreach(nested.Container(), &foreachObserver{observer: obs, index: i, doc: c, prefix: concrete.Path})
+			} else {
+				if obs != nil {
+					err = obs.beforeOp(i, &concrete, c)
+				}
+
+				if err == nil {
+					err = concrete.Perform(c)
+				}
+
+				if obs != nil {
+					obs.afterOp(i, &concrete, c, err)
 				}
 			}
-		} else {
-			err = op.Perform(c)
+
 			if err != nil {
-				return nil, err
+				if opts.ContinueOnError {
+					restore(c, snapshot)
+				}
+
+				if err = fail(i, concrete, err); err != nil {
+					return err
+				}
+				break
 			}
 		}
 	}
 
-	return yaml.Marshal(c)
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+// restore replaces the contents of the container with the value
+func restore(c Container, v interface{}) {
+	switch ct := c.(type) {
+	case *nodeMap:
+		if m, ok := NewNode(&v).Container().(*nodeMap); ok {
+			*ct = *m
+		}
+	case *nodeSlice:
+		if ary, ok := NewNode(&v).Container().(*nodeSlice); ok {
+			*ct = *ary
+		}
+	}
+}
+
+// expand returns an operation for each path matched by the operation's path,
+// with its from expanded to the single path it matches
+func (o *Operation) expand(c Container) ([]Operation, error) {
+	op := *o
+
+	if op.From.ContainsExtendedSyntax() {
+		from, err := expandPointer(c, op.From)
+		if err != nil {
+			return nil, fmt.Errorf("yamlpatch %s operation does not apply: invalid from: %s", op.Op, err)
+		}
+		op.From = from
+	}
+
+	if !op.Path.ContainsExtendedSyntax() {
+		return []Operation{op}, nil
+	}
+
+	paths := NewPathFinder(c).Find(string(op.Path))
+	if paths == nil {
+		switch op.Op {
+		case opTestAbsent:
+			// nothing matching the path is what the operation tests for
+			return []Operation{}, nil
+		case opTestPresent:
+			return nil, fmt.Errorf("test-present failed: doc is missing path: %s: %s", op.Path, NewPathFinder(c).explain(string(op.Path)))
+		}
+
+		return nil, fmt.Errorf("could not expand pointer: %s: %s", op.Path, NewPathFinder(c).explain(string(op.Path)))
+	}
+
+	if op.Op == opMove && len(paths) > 1 {
+		return nil, fmt.Errorf("yamlpatch move operation does not apply: cannot move to %d paths: %s", len(paths), strings.Join(paths, ", "))
+	}
+
+	// removing or inserting an array element shifts the indexes of the
+	// elements after it, so those paths are visited last to first
+	if shiftsIndexes(op.Op, OpPath(paths[0])) {
+		for i, j := 0, len(paths)-1; i < j; i, j = i+1, j-1 {
+			paths[i], paths[j] = paths[j], paths[i]
+		}
+	}
+
+	ops := make([]Operation, len(paths))
+	for i, path := range paths {
+		ops[i] = op
+		ops[i].Path = OpPath(path)
+	}
+
+	return ops, nil
+}
+
+// shiftsIndexes returns whether the operation can change the indexes of other
+// elements of the array at the concrete path
+func shiftsIndexes(op Op, path OpPath) bool {
+	switch op {
+	case opRemove:
+		return true
+	case opAdd:
+		_, key, err := path.Decompose()
+		return err == nil && key != "-" && isIndex(key)
+	}
+
+	return false
 }
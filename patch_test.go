@@ -80,6 +80,43 @@ foo: [bar,qux,baz]
 `,
 				`---
 foo: [bar,baz]
+`,
+			),
+			Entry("removing every array element matched by an extended path",
+				`---
+foo:
+- name: x
+- name: x
+- name: y
+`,
+				`---
+- op: remove
+  path: /foo/name=x
+`,
+				`---
+foo:
+- name: y
+`,
+			),
+			Entry("inserting before every array element matched by an extended path",
+				`---
+foo:
+- name: x
+- name: y
+- name: x
+`,
+				`---
+- op: add
+  path: /foo/name=x
+  value: {name: z}
+`,
+				`---
+foo:
+- name: z
+- name: x
+- name: y
+- name: z
+- name: x
 `,
 			),
 			Entry("replacing an element in an object",
@@ -349,227 +386,1870 @@ foo:
   bar: [bar, qux, baz]
 `,
 			),
-		)
-
-		DescribeTable(
-			"with extended syntax",
-			func(doc, ops, expectedYAML string) {
-				patch, err := yamlpatch.DecodePatch([]byte(ops))
-				Expect(err).NotTo(HaveOccurred())
-
-				actualBytes, err := patch.Apply([]byte(doc))
-				Expect(err).NotTo(HaveOccurred())
-
-				var actualIface interface{}
-				err = yaml.Unmarshal(actualBytes, &actualIface)
-				Expect(err).NotTo(HaveOccurred())
-
-				var expectedIface interface{}
-				err = yaml.Unmarshal([]byte(expectedYAML), &expectedIface)
-				Expect(err).NotTo(HaveOccurred())
-
-				Expect(actualIface).To(Equal(expectedIface))
-			},
-			Entry("a path that begins with a composite key",
+			Entry("merging a map into an object",
 				`---
-- foo: bar
+foo:
+  bar: baz
+  waldo:
+    fred: plugh
 `,
 				`---
-- op: replace
-  path: /foo=bar
+- op: merge
+  path: /foo
   value:
-    baz: quux
+    qux: quux
+    waldo:
+      xyzzy: thud
 `,
 				`---
-- baz: quux
+foo:
+  bar: baz
+  qux: quux
+  waldo:
+    fred: plugh
+    xyzzy: thud
 `,
 			),
-			Entry("a path that begins with an array index and ends with a composite key",
+			Entry("merging into a nonexistent key",
 				`---
-- waldo:
-    - thud: boo
-    - foo: bar
-- corge: grault
+foo: bar
 `,
 				`---
-- op: replace
-  path: /0/foo=bar
+- op: merge
+  path: /baz
   value:
-    baz: quux
+    qux: quux
 `,
 				`---
-- waldo:
-    - thud: boo
-    - baz: quux
-- corge: grault
+foo: bar
+baz:
+  qux: quux
 `,
 			),
-			Entry("a path that begins with an object key and ends with a composite key",
+			Entry("merging a list with the default strategy",
 				`---
-waldo:
-  - thud: boo
-  - foo: bar
-corge: grault
+foo:
+  bar: [a, b]
 `,
 				`---
-- op: replace
-  path: /waldo/foo=bar
+- op: merge
+  path: /foo
   value:
-    baz: quux
+    bar: [c]
 `,
 				`---
-waldo:
-  - thud: boo
-  - baz: quux
-corge: grault
+foo:
+  bar: [c]
 `,
 			),
-			Entry("a path that doesn't end with a composite key",
+			Entry("merging a list with the append strategy",
+				`---
+foo:
+  bar: [a, b]
+`,
+				`---
+- op: merge
+  path: /foo
+  strategy: append
+  value:
+    bar: [c]
+`,
+				`---
+foo:
+  bar: [a, b, c]
+`,
+			),
+			Entry("merging a list with the prepend strategy",
+				`---
+foo: [a, b]
+`,
+				`---
+- op: merge
+  path: /foo
+  strategy: prepend
+  value: [c]
+`,
+				`---
+foo: [c, a, b]
+`,
+			),
+			Entry("merging a list by key",
 				`---
 jobs:
-- name: upgrade-opsmgr
+- name: job1
   serial: true
   plan:
-  - get: pivnet-opsmgr
-  - put: something-else
+  - get: A
+- name: job2
 `,
 				`---
-- op: replace
-  path: /jobs/name=upgrade-opsmgr/plan/1
+- op: merge
+  path: /jobs
+  strategy: merge
+  key: name
   value:
-    get: something-else
+  - name: job1
+    serial: false
+    plan:
+    - get: B
+  - name: job3
 `,
 				`---
 jobs:
-- name: upgrade-opsmgr
-  serial: true
+- name: job1
+  serial: false
   plan:
-  - get: pivnet-opsmgr
-  - get: something-else
+  - get: A
+  - get: B
+- name: job2
+- name: job3
 `,
 			),
-			Entry("removes multiple entries in a single op",
+			Entry("extending an array",
+				`---
+foo: [bar]
+`,
+				`---
+- op: extend
+  path: /foo
+  value: [abc, def]
+`,
+				`---
+foo: [bar, abc, def]
+`,
+			),
+			Entry("adding a unique element to an array",
+				`---
+foo: [bar]
+`,
+				`---
+- op: add-unique
+  path: /foo
+  value: baz
+`,
+				`---
+foo: [bar, baz]
+`,
+			),
+			Entry("adding an element that is already present to an array",
 				`---
 foo:
-  - bar: baz
-  - waldo: fred
-qux:
-  corge: grault
-  thud:
-    - waldo: fred
-    - bar: baz
+- name: bar
+  value: 1
 `,
 				`---
-- op: remove
-  path: /waldo=fred
+- op: add-unique
+  path: /foo
+  value:
+    name: bar
+    value: 1
 `,
 				`---
 foo:
-  - bar: baz
-qux:
-  corge: grault
-  thud:
-    - bar: baz
+- name: bar
+  value: 1
 `,
 			),
-		)
-
-		DescribeTable(
-			"failure cases",
-			func(doc, ops string) {
-				patch, err := yamlpatch.DecodePatch([]byte(ops))
-				Expect(err).NotTo(HaveOccurred())
-
-				_, err = patch.Apply([]byte(doc))
-				Expect(err).To(HaveOccurred())
-			},
-			Entry("adding an element to an object with a bad pointer",
+			Entry("adding a unique element to an array modified by a previous op",
 				`---
-foo: bar
+foo: [[bar]]
 `,
 				`---
 - op: add
-  path: /baz/bat
-  value: qux
+  path: /foo/0/-
+  value: baz
+- op: add-unique
+  path: /foo
+  value: [bar, baz]
+`,
+				`---
+foo: [[bar, baz]]
 `,
 			),
-			Entry("removing an element from an object with a bad pointer",
+			Entry("removing all elements equal to a value from an array",
 				`---
-a:
-  b:
-    d: 1
+foo: [bar, baz, bar]
 `,
 				`---
-- op: remove
-  path: /a/b/c
+- op: remove-value
+  path: /foo
+  value: bar
+`,
+				`---
+foo: [baz]
 `,
 			),
-			Entry("moving an element in an object with a bad pointer",
+			Entry("removing a value that is not present from an array",
 				`---
-a:
-  b:
-    d: 1
+foo: [bar]
 `,
 				`---
-- op: move
-  from: /a/b/c
-  path: /a/b/e
+- op: remove-value
+  path: /foo
+  value: baz
+`,
+				`---
+foo: [bar]
 `,
 			),
-			Entry("removing an element from an array with a bad pointer",
+			Entry("renaming a key in an object",
 				`---
-a:
-  b: [1]
+foo:
+  bar: baz
+  waldo: fred
 `,
 				`---
-- op: remove
-  path: /a/b/1
+- op: rename
+  path: /foo/bar
+  to: qux
+`,
+				`---
+foo:
+  qux: baz
+  waldo: fred
 `,
 			),
-			Entry("moving an element from an array with a bad pointer",
+			Entry("renaming a key to an existing key with overwrite",
 				`---
-a:
-  b: [1]
+foo:
+  bar: baz
+  waldo: fred
 `,
 				`---
-- op: move
-  from: /a/b/1
-  path: /a/b/2
+- op: rename
+  path: /foo/bar
+  to: waldo
+  overwrite: true
+`,
+				`---
+foo:
+  waldo: baz
 `,
 			),
-			Entry("an operation with an invalid pathz field",
+			Entry("sorting an array of scalars",
 				`---
-foo: bar
+foo: [c, a, b]
 `,
 				`---
-- op: add
-  pathz: /baz
-  value: qux
+- op: sort
+  path: /foo
+`,
+				`---
+foo: [a, b, c]
 `,
 			),
-			Entry("an add operation with an empty path",
+			Entry("sorting an array of numbers in descending order",
 				`---
-foo: bar
+foo: [2, 10, 1.5]
 `,
 				`---
-- op: add
-  path: ''
-  value: qux
+- op: sort
+  path: /foo
+  order: desc
+`,
+				`---
+foo: [10, 2, 1.5]
 `,
 			),
-			Entry("a replace operation on an array with an invalid path",
+			Entry("sorting an array of objects by key",
 				`---
-name:
-  foo:
-    bat
-  qux:
-    bum
+foo:
+- name: b
+  id: 1
+- name: a
+- name: b
+  id: 2
 `,
 				`---
-- op: replace
-  path: /foo/2
-  value: bum
+- op: sort
+  path: /foo
+  key: name
+`,
+				`---
+foo:
+- name: a
+- name: b
+  id: 1
+- name: b
+  id: 2
+`,
+			),
+			Entry("deduping an array",
+				`---
+foo: [a, b, a, [c], [c]]
+`,
+				`---
+- op: dedupe
+  path: /foo
+`,
+				`---
+foo: [a, b, [c]]
+`,
+			),
+			Entry("deduping an array of objects by key",
+				`---
+foo:
+- name: a
+  id: 1
+- id: 2
+- name: a
+  id: 3
+- id: 4
+`,
+				`---
+- op: dedupe
+  path: /foo
+  key: name
+`,
+				`---
+foo:
+- name: a
+  id: 1
+- id: 2
+- id: 4
+`,
+			),
+			Entry("transforming a string with a regular expression",
+				`---
+image: registry.example.com/app:1.2.3
+`,
+				`---
+- op: transform
+  path: /image
+  function: replace
+  pattern: ':(\d+)\.(\d+)\.\d+$'
+  value: ':${1}.${2}.4'
+`,
+				`---
+image: registry.example.com/app:1.2.4
+`,
+			),
+			Entry("adding a prefix and a suffix to a string",
+				`---
+url: example.com
+`,
+				`---
+- op: transform
+  path: /url
+  function: add-prefix
+  value: https://
+- op: transform
+  path: /url
+  function: add-suffix
+  value: /api
+`,
+				`---
+url: https://example.com/api
+`,
+			),
+			Entry("stripping a prefix and a suffix from a string",
+				`---
+version: v1.2.3-rc
+`,
+				`---
+- op: transform
+  path: /version
+  function: strip-prefix
+  value: v
+- op: transform
+  path: /version
+  function: strip-suffix
+  value: -rc
+`,
+				`---
+version: 1.2.3
+`,
+			),
+			Entry("changing the case of strings",
+				`---
+foo: Bar
+baz: Qux
+`,
+				`---
+- op: transform
+  path: /foo
+  function: upper
+- op: transform
+  path: /baz
+  function: lower
+`,
+				`---
+foo: BAR
+baz: qux
+`,
+			),
+			Entry("formatting a string with a template",
+				`---
+tag: "1.2"
+`,
+				`---
+- op: transform
+  path: /tag
+  function: format
+  value: 'v{{ . }}-{{ printf "%s" . | len }}'
+`,
+				`---
+tag: v1.2-3
+`,
+			),
+			Entry("incrementing and decrementing integers",
+				`---
+foo: 1
+bar: 10
+`,
+				`---
+- op: increment
+  path: /foo
+- op: decrement
+  path: /bar
+  value: 3
+`,
+				`---
+foo: 2
+bar: 7
+`,
+			),
+			Entry("multiplying an integer by a float",
+				`---
+foo: 3
+`,
+				`---
+- op: multiply
+  path: /foo
+  value: 1.5
+`,
+				`---
+foo: 4.5
+`,
+			),
+			Entry("multiplying a float to an integer value",
+				`---
+foo: 1.5
+`,
+				`---
+- op: multiply
+  path: /foo
+  value: 2
+`,
+				`---
+foo: 3.0
+`,
+			),
+			Entry("keeping floats with integer values beside non-ASCII keys",
+				`---
+café: 1.5
+naïve: 2.0
+`,
+				`---
+- op: multiply
+  path: /café
+  value: 2
+`,
+				`---
+café: 3.0
+naïve: 2.0
+`,
+			),
+			Entry("clamping numbers with min and max",
+				`---
+foo: 10
+bar: 0.5
+`,
+				`---
+- op: min
+  path: /foo
+  value: 5
+- op: max
+  path: /bar
+  value: 1.25
+`,
+				`---
+foo: 5
+bar: 1.25
+`,
+			),
+			Entry("adding a value from another path",
+				`---
+defaults:
+  timeout: 30
+foo: {}
+`,
+				`---
+- op: add
+  path: /foo/timeout
+  valueFrom: /defaults/timeout
+`,
+				`---
+defaults:
+  timeout: 30
+foo:
+  timeout: 30
+`,
+			),
+			Entry("adding a value with an embedded reference",
+				`---
+a: bar
+`,
+				`---
+- op: add
+  path: /b
+  resolveRefs: true
+  value:
+  - $ref: /a
+  - baz
+`,
+				`---
+a: bar
+b: [bar, baz]
+`,
+			),
+			Entry("adding a value with a $ref without resolving references",
+				`---
+definitions:
+  Person: {type: object}
+`,
+				`---
+- op: add
+  path: /definitions/Pet
+  value:
+    properties:
+      owner: {$ref: '#/definitions/Person'}
+`,
+				`---
+definitions:
+  Person: {type: object}
+  Pet:
+    properties:
+      owner: {$ref: '#/definitions/Person'}
+`,
+			),
+			Entry("copying a value from another path that is later modified",
+				`---
+a: [1]
+`,
+				`---
+- op: add
+  path: /b
+  valueFrom: /a
+- op: add
+  path: /a/-
+  value: 2
+`,
+				`---
+a: [1, 2]
+b: [1]
+`,
+			),
+			Entry("testing for the absence and presence of keys",
+				`---
+foo:
+  bar: ~
+`,
+				`---
+- op: test-absent
+  path: /foo/baz
+- op: test-absent
+  path: /qux/baz
+- op: test-present
+  path: /foo/bar
+`,
+				`---
+foo:
+  bar: ~
+`,
+			),
+			Entry("testing for the absence and presence of keys with extended syntax",
+				`---
+jobs:
+- name: job1
+`,
+				`---
+- op: test-absent
+  path: /jobs/name=job2
+- op: test-present
+  path: /jobs/name=job1
+- op: add
+  path: /jobs/-
+  value: {name: job2}
+`,
+				`---
+jobs:
+- name: job1
+- name: job2
+`,
+			),
+			Entry("testing the types of values",
+				`---
+a: {}
+b: []
+c: foo
+d: 1
+e: 1.5
+f: true
+g: ~
+`,
+				`---
+- {op: test-type, path: /a, value: map}
+- {op: test-type, path: /b, value: list}
+- {op: test-type, path: /c, value: string}
+- {op: test-type, path: /d, value: int}
+- {op: test-type, path: /e, value: float}
+- {op: test-type, path: /f, value: bool}
+- {op: test-type, path: /g, value: null}
+`,
+				`---
+a: {}
+b: []
+c: foo
+d: 1
+e: 1.5
+f: true
+g: ~
+`,
+			),
+			Entry("testing values against a regular expression, range and length",
+				`---
+version: v1.2.3
+port: 8080
+hosts: [a, b, c]
+`,
+				`---
+- op: test-regex
+  path: /version
+  pattern: '^v1\.'
+- op: test-range
+  path: /port
+  min: 1024
+  max: 65535
+- op: test-length
+  path: /hosts
+  value: 3
+- op: test-length
+  path: /hosts
+  min: 1
+`,
+				`---
+version: v1.2.3
+port: 8080
+hosts: [a, b, c]
+`,
+			),
+			Entry("testing whether a list contains a value",
+				`---
+foo: [a, {name: b}]
+`,
+				`---
+- op: test-contains
+  path: /foo
+  value: {name: b}
+`,
+				`---
+foo: [a, {name: b}]
+`,
+			),
+		)
+
+		DescribeTable(
+			"with extended syntax",
+			func(doc, ops, expectedYAML string) {
+				patch, err := yamlpatch.DecodePatch([]byte(ops))
+				Expect(err).NotTo(HaveOccurred())
+
+				actualBytes, err := patch.Apply([]byte(doc))
+				Expect(err).NotTo(HaveOccurred())
+
+				var actualIface interface{}
+				err = yaml.Unmarshal(actualBytes, &actualIface)
+				Expect(err).NotTo(HaveOccurred())
+
+				var expectedIface interface{}
+				err = yaml.Unmarshal([]byte(expectedYAML), &expectedIface)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(actualIface).To(Equal(expectedIface))
+			},
+			Entry("a path that begins with a composite key",
+				`---
+- foo: bar
+`,
+				`---
+- op: replace
+  path: /foo=bar
+  value:
+    baz: quux
+`,
+				`---
+- baz: quux
+`,
+			),
+			Entry("a path that begins with an array index and ends with a composite key",
+				`---
+- waldo:
+    - thud: boo
+    - foo: bar
+- corge: grault
+`,
+				`---
+- op: replace
+  path: /0/foo=bar
+  value:
+    baz: quux
+`,
+				`---
+- waldo:
+    - thud: boo
+    - baz: quux
+- corge: grault
+`,
+			),
+			Entry("a path that begins with an object key and ends with a composite key",
+				`---
+waldo:
+  - thud: boo
+  - foo: bar
+corge: grault
+`,
+				`---
+- op: replace
+  path: /waldo/foo=bar
+  value:
+    baz: quux
+`,
+				`---
+waldo:
+  - thud: boo
+  - baz: quux
+corge: grault
+`,
+			),
+			Entry("a path that doesn't end with a composite key",
+				`---
+jobs:
+- name: upgrade-opsmgr
+  serial: true
+  plan:
+  - get: pivnet-opsmgr
+  - put: something-else
+`,
+				`---
+- op: replace
+  path: /jobs/name=upgrade-opsmgr/plan/1
+  value:
+    get: something-else
+`,
+				`---
+jobs:
+- name: upgrade-opsmgr
+  serial: true
+  plan:
+  - get: pivnet-opsmgr
+  - get: something-else
+`,
+			),
+			Entry("removes multiple entries in a single op",
+				`---
+foo:
+  - bar: baz
+  - waldo: fred
+qux:
+  corge: grault
+  thud:
+    - waldo: fred
+    - bar: baz
+`,
+				`---
+- op: remove
+  path: /waldo=fred
+`,
+				`---
+foo:
+  - bar: baz
+qux:
+  corge: grault
+  thud:
+    - bar: baz
+`,
+			),
+			Entry("merging into an object matched by extended syntax",
+				`---
+jobs:
+- name: job1
+  plan:
+  - get: A
+    params:
+      globs: ["*.tgz"]
+`,
+				`---
+- op: merge
+  path: /jobs/get=A
+  value:
+    params:
+      unpack: true
+`,
+				`---
+jobs:
+- name: job1
+  plan:
+  - get: A
+    params:
+      globs: ["*.tgz"]
+      unpack: true
+`,
+			),
+			Entry("sorting an array matched by extended syntax",
+				`---
+jobs:
+- name: job1
+  plan:
+  - get: C
+  - get: A
+- name: job2
+  plan:
+  - get: B
+  - get: A
+`,
+				`---
+- op: sort
+  path: /jobs/name=job2/plan
+  key: get
+`,
+				`---
+jobs:
+- name: job1
+  plan:
+  - get: C
+  - get: A
+- name: job2
+  plan:
+  - get: A
+  - get: B
+`,
+			),
+			Entry("transforming every matched string",
+				`---
+jobs:
+- name: job1
+  plan:
+  - get: A
+    version: v1
+- name: job2
+  plan:
+  - get: A
+    version: v2
+`,
+				`---
+- op: transform
+  path: /jobs/get=A/version
+  function: upper
+`,
+				`---
+jobs:
+- name: job1
+  plan:
+  - get: A
+    version: V1
+- name: job2
+  plan:
+  - get: A
+    version: V2
+`,
+			),
+			Entry("adding a value from a path using extended syntax",
+				`---
+jobs:
+- name: job1
+  serial: true
+- name: job2
+`,
+				`---
+- op: add
+  path: /jobs/name=job2/serial
+  valueFrom: /jobs/name=job1/serial
+`,
+				`---
+jobs:
+- name: job1
+  serial: true
+- name: job2
+  serial: true
+`,
+			),
+			Entry("performing several operations on each matched element",
+				`---
+jobs:
+- name: job1
+  plan:
+  - get: A
+    trigger: false
+  - get: B
+- name: job2
+  plan:
+  - get: A
+    trigger: false
+`,
+				`---
+- op: foreach
+  path: /jobs/get=A
+  ops:
+  - op: remove
+    path: /trigger
+  - op: add
+    path: /passed
+    value: [build]
+  - op: add
+    path: /version
+    value: every
+`,
+				`---
+jobs:
+- name: job1
+  plan:
+  - get: A
+    passed: [build]
+    version: every
+  - get: B
+- name: job2
+  plan:
+  - get: A
+    passed: [build]
+    version: every
+`,
+			),
+			Entry("performing operations with extended syntax on each matched element",
+				`---
+jobs:
+- name: job1
+  plan:
+  - get: A
+  - put: B
+- name: job2
+  plan:
+  - put: B
+`,
+				`---
+- op: foreach
+  path: /jobs/name=job1
+  ops:
+  - op: add
+    path: /put=B/params
+    value: {file: out}
+  - op: add
+    path: /serial
+    value: true
+`,
+				`---
+jobs:
+- name: job1
+  serial: true
+  plan:
+  - get: A
+  - put: B
+    params: {file: out}
+- name: job2
+  plan:
+  - put: B
+`,
+			),
+			Entry("moving an element matched by extended syntax",
+				`---
+jobs:
+- name: job1
+  plan:
+  - get: A
+    trigger: true
+  - get: B
+`,
+				`---
+- op: move
+  from: /jobs/get=A/trigger
+  path: /jobs/get=B/trigger
+`,
+				`---
+jobs:
+- name: job1
+  plan:
+  - get: A
+  - get: B
+    trigger: true
+`,
+			),
+			Entry("copying an element matched by extended syntax to each match",
+				`---
+defaults:
+- name: timeout
+  value: 5m
+jobs:
+- name: job1
+  plan:
+  - task: A
+  - task: B
+`,
+				`---
+- op: copy
+  from: /defaults/name=timeout/value
+  path: /jobs/name=job1/plan/task=A/timeout
+- op: copy
+  from: /defaults/name=timeout
+  path: /jobs/task=B/params
+`,
+				`---
+defaults:
+- name: timeout
+  value: 5m
+jobs:
+- name: job1
+  plan:
+  - task: A
+    timeout: 5m
+  - task: B
+    params:
+      name: timeout
+      value: 5m
+`,
+			),
+		)
+
+		DescribeTable(
+			"failure cases",
+			func(doc, ops string) {
+				patch, err := yamlpatch.DecodePatch([]byte(ops))
+				Expect(err).NotTo(HaveOccurred())
+
+				_, err = patch.Apply([]byte(doc))
+				Expect(err).To(HaveOccurred())
+			},
+			Entry("adding an element to an object with a bad pointer",
+				`---
+foo: bar
+`,
+				`---
+- op: add
+  path: /baz/bat
+  value: qux
+`,
+			),
+			Entry("removing an element from an object with a bad pointer",
+				`---
+a:
+  b:
+    d: 1
+`,
+				`---
+- op: remove
+  path: /a/b/c
+`,
+			),
+			Entry("moving an element in an object with a bad pointer",
+				`---
+a:
+  b:
+    d: 1
+`,
+				`---
+- op: move
+  from: /a/b/c
+  path: /a/b/e
+`,
+			),
+			Entry("removing an element from an array with a bad pointer",
+				`---
+a:
+  b: [1]
+`,
+				`---
+- op: remove
+  path: /a/b/1
+`,
+			),
+			Entry("moving an element from an array with a bad pointer",
+				`---
+a:
+  b: [1]
+`,
+				`---
+- op: move
+  from: /a/b/1
+  path: /a/b/2
+`,
+			),
+			Entry("an operation with an invalid pathz field",
+				`---
+foo: bar
+`,
+				`---
+- op: add
+  pathz: /baz
+  value: qux
+`,
+			),
+			Entry("an add operation with an empty path",
+				`---
+foo: bar
+`,
+				`---
+- op: add
+  path: ''
+  value: qux
+`,
+			),
+			Entry("a replace operation on an array with an invalid path",
+				`---
+name:
+  foo:
+    bat
+  qux:
+    bum
+`,
+				`---
+- op: replace
+  path: /foo/2
+  value: bum
+`,
+			),
+			Entry("merging into an object with a bad pointer",
+				`---
+foo: bar
+`,
+				`---
+- op: merge
+  path: /baz/bat
+  value:
+    qux: quux
+`,
+			),
+			Entry("merging a list by key without a key",
+				`---
+foo: [a]
+`,
+				`---
+- op: merge
+  path: /foo
+  strategy: merge
+  value: [b]
+`,
+			),
+			Entry("merging with an unknown list strategy",
+				`---
+foo: [a]
+`,
+				`---
+- op: merge
+  path: /foo
+  strategy: interleave
+  value: [b]
+`,
+			),
+			Entry("extending an array with a value that is not an array",
+				`---
+foo: [bar]
+`,
+				`---
+- op: extend
+  path: /foo
+  value: baz
+`,
+			),
+			Entry("extending something that is not an array",
+				`---
+foo: bar
+`,
+				`---
+- op: extend
+  path: /foo
+  value: [baz]
+`,
+			),
+			Entry("adding a unique element without a value",
+				`---
+foo: [bar]
+`,
+				`---
+- op: add-unique
+  path: /foo
+`,
+			),
+			Entry("removing elements equal to a value without a value",
+				`---
+foo: [bar, ~]
+`,
+				`---
+- op: remove-value
+  path: /foo
+`,
+			),
+			Entry("adding a unique element to a nonexistent array",
+				`---
+foo: [bar]
+`,
+				`---
+- op: add-unique
+  path: /baz
+  value: qux
+`,
+			),
+			Entry("renaming a key to an existing key",
+				`---
+foo:
+  bar: baz
+  waldo: fred
+`,
+				`---
+- op: rename
+  path: /foo/bar
+  to: waldo
+`,
+			),
+			Entry("renaming a nonexistent key",
+				`---
+foo:
+  bar: baz
+`,
+				`---
+- op: rename
+  path: /foo/qux
+  to: waldo
+`,
+			),
+			Entry("renaming an element of an array",
+				`---
+foo: [bar]
+`,
+				`---
+- op: rename
+  path: /foo/0
+  to: waldo
+`,
+			),
+			Entry("sorting an array with an unexpected order",
+				`---
+foo: [b, a]
+`,
+				`---
+- op: sort
+  path: /foo
+  order: sideways
+`,
+			),
+			Entry("deduping something that is not an array",
+				`---
+foo:
+  bar: baz
+`,
+				`---
+- op: dedupe
+  path: /foo
+`,
+			),
+			Entry("transforming a value that is not a string",
+				`---
+foo: 1
+`,
+				`---
+- op: transform
+  path: /foo
+  function: upper
+`,
+			),
+			Entry("transforming a nonexistent value",
+				`---
+foo: bar
+`,
+				`---
+- op: transform
+  path: /baz
+  function: upper
+`,
+			),
+			Entry("transforming a string with an unknown function",
+				`---
+foo: bar
+`,
+				`---
+- op: transform
+  path: /foo
+  function: reverse
+`,
+			),
+			Entry("transforming a string with an invalid regular expression",
+				`---
+foo: bar
+`,
+				`---
+- op: transform
+  path: /foo
+  function: replace
+  pattern: '('
+  value: baz
+`,
+			),
+			Entry("incrementing a value that is not numeric",
+				`---
+foo: bar
+`,
+				`---
+- op: increment
+  path: /foo
+`,
+			),
+			Entry("incrementing an integer past the largest integer",
+				`---
+foo: 9223372036854775807
+`,
+				`---
+- op: increment
+  path: /foo
+  value: 5
+`,
+			),
+			Entry("multiplying an integer past the largest integer",
+				`---
+foo: 4611686018427387904
+`,
+				`---
+- op: multiply
+  path: /foo
+  value: -4
+`,
+			),
+			Entry("multiplying by a value that is not numeric",
+				`---
+foo: 1
+`,
+				`---
+- op: multiply
+  path: /foo
+  value: bar
+`,
+			),
+			Entry("multiplying without a value",
+				`---
+foo: 1
+`,
+				`---
+- op: multiply
+  path: /foo
+`,
+			),
+			Entry("adding a value from a nonexistent path",
+				`---
+foo: bar
+`,
+				`---
+- op: add
+  path: /baz
+  valueFrom: /qux
+`,
+			),
+			Entry("adding a value from an ambiguous path",
+				`---
+foo:
+- name: a
+- name: a
+`,
+				`---
+- op: add
+  path: /baz
+  valueFrom: /foo/name=a
+`,
+			),
+			Entry("adding a value with both value and valueFrom",
+				`---
+foo: bar
+`,
+				`---
+- op: add
+  path: /baz
+  value: qux
+  valueFrom: /foo
+`,
+			),
+			Entry("adding a value with a reference to a nonexistent path",
+				`---
+foo: bar
+`,
+				`---
+- op: add
+  path: /baz
+  resolveRefs: true
+  value:
+    $ref: /qux
+`,
+			),
+			Entry("resolving references without a value",
+				`---
+foo: bar
+`,
+				`---
+- op: add
+  path: /baz
+  resolveRefs: true
+`,
+			),
+			Entry("performing a foreach on a scalar",
+				`---
+foo: bar
+`,
+				`---
+- op: foreach
+  path: /foo
+  ops:
+  - op: add
+    path: /baz
+    value: qux
+`,
+			),
+			Entry("performing a foreach with a failing nested operation",
+				`---
+foo:
+  bar: baz
+`,
+				`---
+- op: foreach
+  path: /foo
+  ops:
+  - op: remove
+    path: /qux
+`,
+			),
+			Entry("moving from an ambiguous pointer",
+				`---
+foo:
+- name: a
+- name: a
+bar: []
+`,
+				`---
+- op: move
+  from: /foo/name=a
+  path: /bar/-
+`,
+			),
+			Entry("copying from a pointer that matches nothing",
+				`---
+foo:
+- name: a
+`,
+				`---
+- op: copy
+  from: /foo/name=b
+  path: /bar
+`,
+			),
+			Entry("moving to more than one path",
+				`---
+foo:
+- name: a
+- name: a
+bar: baz
+`,
+				`---
+- op: move
+  from: /bar
+  path: /foo/name=a/bar
+`,
+			),
+			Entry("testing for the absence of a key that exists",
+				`---
+foo: ~
+`,
+				`---
+- op: test-absent
+  path: /foo
+`,
+			),
+			Entry("testing for the presence of a key that does not exist",
+				`---
+foo: bar
+`,
+				`---
+- op: test-present
+  path: /baz
+`,
+			),
+			Entry("testing for the wrong type",
+				`---
+foo: "1"
+`,
+				`---
+- op: test-type
+  path: /foo
+  value: int
+`,
+			),
+			Entry("testing for an unknown type",
+				`---
+foo: bar
+`,
+				`---
+- op: test-type
+  path: /foo
+  value: object
+`,
+			),
+			Entry("testing a value against a regular expression it does not match",
+				`---
+version: v2.0.0
+`,
+				`---
+- op: test-regex
+  path: /version
+  pattern: '^v1\.'
+`,
+			),
+			Entry("testing a value that is out of range",
+				`---
+port: 80
+`,
+				`---
+- op: test-range
+  path: /port
+  min: 1024
+`,
+			),
+			Entry("testing the range of a value that is not numeric",
+				`---
+port: http
+`,
+				`---
+- op: test-range
+  path: /port
+  max: 1024
+`,
+			),
+			Entry("testing the length of a list",
+				`---
+foo: [a, b]
+`,
+				`---
+- op: test-length
+  path: /foo
+  value: 3
+`,
+			),
+			Entry("testing whether a list contains a value it does not contain",
+				`---
+foo: [a, b]
+`,
+				`---
+- op: test-contains
+  path: /foo
+  value: c
+`,
+			),
+			Entry("testing whether a value that is not a list contains a value",
+				`---
+foo: a
+`,
+				`---
+- op: test-contains
+  path: /foo
+  value: a
+`,
+			),
+		)
+	})
+
+	Describe("ApplyWithOptions", func() {
+		var patch yamlpatch.Patch
+
+		BeforeEach(func() {
+			var err error
+			patch, err = yamlpatch.DecodePatch([]byte(`---
+- op: add
+  path: /baz/bat
+  value: qux
+- op: add
+  path: /waldo
+  value: fred
+- op: test
+  path: /jobs/name=job1/plan
+  value: [get: A]
+- op: replace
+  path: /jobs/name=job2/serial
+  value: true
+`))
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("stops at the first failing operation", func() {
+			_, err := patch.ApplyWithOptions([]byte(`---
+jobs:
+- name: job1
+  plan: []
+`), yamlpatch.ApplyOptions{})
+			Expect(err).To(MatchError(`operation 0 (add /baz/bat) at line 2: yamlpatch add operation does not apply: doc is missing path: /baz/bat: / has no key "baz" (available keys: jobs)`))
+		})
+
+		It("reports the closest path in the document to the path of a failing operation", func() {
+			_, err := patch.ApplyWithOptions([]byte(`---
+jobs:
+- name: job1
+  plan: []
+baz:
+  qux: quux
+`), yamlpatch.ApplyOptions{ContinueOnError: true})
+
+			errs, ok := err.(yamlpatch.ApplyErrors)
+			Expect(ok).To(BeTrue())
+			Expect(errs).To(HaveLen(2))
+			Expect(errs[0].Source).To(Equal(yamlpatch.Source{Index: 2, Line: 8, Column: 3}))
+			Expect(errs[0].DocPath).To(Equal(yamlpatch.OpPath("/jobs/0/plan")))
+			Expect(errs[0].DocLine).To(Equal(4))
+			Expect(errs[1].Error()).To(Equal(`operation 3 (replace /jobs/name=job2/serial) at line 11: could not expand pointer: /jobs/name=job2/serial: nothing under /jobs has name=job2 (values of name: job1); did you mean "name=job1"? (closest existing path in the document: /jobs, line 2)`))
+		})
+
+		It("does not report the line of a path that earlier operations changed", func() {
+			patch, err := yamlpatch.DecodePatch([]byte(`---
+- op: add
+  path: /jobs/0
+  value: {name: job0}
+- op: replace
+  path: /jobs/0/plan/0
+  value: {get: A}
+- op: replace
+  path: /jobs/1/plan/0
+  value: {get: A}
+`))
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = patch.ApplyWithOptions([]byte(`---
+jobs:
+- name: job1
+  plan: []
+`), yamlpatch.ApplyOptions{ContinueOnError: true})
+
+			errs, ok := err.(yamlpatch.ApplyErrors)
+			Expect(ok).To(BeTrue())
+			Expect(errs).To(HaveLen(2))
+			Expect(errs[0].DocPath).To(Equal(yamlpatch.OpPath("/jobs/0")))
+			Expect(errs[0].DocLine).To(Equal(0))
+			Expect(errs[0].Error()).To(HaveSuffix("(closest existing path in the document: /jobs/0)"))
+			Expect(errs[1].DocPath).To(Equal(yamlpatch.OpPath("/jobs/1/plan")))
+			Expect(errs[1].DocLine).To(Equal(0))
+		})
+
+		It("reports the line of a path that earlier operations did not change", func() {
+			patch, err := yamlpatch.DecodePatch([]byte(`---
+- op: add
+  path: /jobs/-
+  value: {name: job2}
+- op: replace
+  path: /jobs/0/plan/0
+  value: {get: A}
+`))
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = patch.Apply([]byte(`---
+jobs:
+- name: job1
+  plan: []
+`))
+
+			applyErr, ok := err.(*yamlpatch.ApplyError)
+			Expect(ok).To(BeTrue())
+			Expect(applyErr.DocPath).To(Equal(yamlpatch.OpPath("/jobs/0/plan")))
+			Expect(applyErr.DocLine).To(Equal(4))
+		})
+
+		It("applies the remaining operations and returns every failure when continuing on error", func() {
+			actualBytes, err := patch.ApplyWithOptions([]byte(`---
+jobs:
+- name: job1
+  plan: []
+`), yamlpatch.ApplyOptions{ContinueOnError: true})
+			Expect(err).To(HaveOccurred())
+
+			errs, ok := err.(yamlpatch.ApplyErrors)
+			Expect(ok).To(BeTrue())
+			Expect(errs).To(HaveLen(3))
+			Expect(errs[0].Index).To(Equal(0))
+			Expect(errs[0].Path).To(Equal(yamlpatch.OpPath("/baz/bat")))
+			Expect(errs[1].Index).To(Equal(2))
+			Expect(errs[1].Path).To(Equal(yamlpatch.OpPath("/jobs/0/plan")))
+			Expect(errs[2].Index).To(Equal(3))
+			Expect(errs[2].Path).To(Equal(yamlpatch.OpPath("/jobs/name=job2/serial")))
+			Expect(err.Error()).To(HavePrefix("3 operations failed:\n\toperation 0 (add /baz/bat) at line 2: "))
+
+			var actualIface interface{}
+			err = yaml.Unmarshal(actualBytes, &actualIface)
+			Expect(err).NotTo(HaveOccurred())
+
+			var expectedIface interface{}
+			err = yaml.Unmarshal([]byte(`---
+waldo: fred
+jobs:
+- name: job1
+  plan: []
+`), &expectedIface)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(actualIface).To(Equal(expectedIface))
+		})
+
+		DescribeTable(
+			"leaves the document as it was when an operation fails while continuing on error",
+			func(ops string) {
+				doc := `---
+a: 1
+jobs:
+- {name: job1, tier: web, owner: core}
+- {name: job2, tier: web}
+`
+				patch, err := yamlpatch.DecodePatch([]byte(ops))
+				Expect(err).NotTo(HaveOccurred())
+
+				actualBytes, err := patch.ApplyWithOptions([]byte(doc), yamlpatch.ApplyOptions{ContinueOnError: true})
+				Expect(err).To(HaveOccurred())
+				Expect(actualBytes).To(MatchYAML(doc))
+			},
+			Entry("for a move to a missing path",
+				`---
+- op: move
+  from: /a
+  path: /missing/a
+`,
+			),
+			Entry("for an operation that fails at only some of its paths",
+				`---
+- op: replace
+  path: /jobs/tier=web/owner
+  value: platform
+`,
+			),
+		)
+	})
+
+	Describe("error messages", func() {
+		doc := `---
+jobs:
+- name: upgrade-opsmgr
+  plan:
+  - get: pivnet
+- name: export-installation
+  plan: []
+`
+
+		DescribeTable(
+			"for missing paths",
+			func(ops, message string) {
+				patch, err := yamlpatch.DecodePatch([]byte(ops))
+				Expect(err).NotTo(HaveOccurred())
+
+				_, err = patch.Apply([]byte(doc))
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring(message))
+			},
+			Entry("name the first missing segment and suggest keys close to it",
+				`---
+- op: add
+  path: /jobs/0/plna/-
+  value: {get: tile}
+`,
+				`doc is missing path: /jobs/0/plna/-: /jobs/0 has no key "plna" (available keys: name, plan); did you mean "plan"?`,
+			),
+			Entry("suggest keys close to a missing key being replaced",
+				`---
+- op: replace
+  path: /job
+  value: []
+`,
+				`doc is missing key: /job: / has no key "job" (available keys: jobs); did you mean "jobs"?`,
+			),
+			Entry("fail a test-present on an extended path that matches nothing",
+				`---
+- op: test-present
+  path: /jobs/name=deploy
+`,
+				`operation 0 (test-present /jobs/name=deploy) at line 2: test-present failed: doc is missing path: /jobs/name=deploy: nothing under /jobs has name=deploy`,
+			),
+			Entry("not suggest keys that are not close",
+				`---
+- op: remove
+  path: /resources/0
+`,
+				`doc is missing path: /resources/0: / has no key "resources" (available keys: jobs)`,
+			),
+			Entry("give the length of an array when an index is out of range",
+				`---
+- op: add
+  path: /jobs/2/serial
+  value: true
+`,
+				`doc is missing path: /jobs/2/serial: /jobs has no index 2 (it has 2 elements)`,
+			),
+			Entry("say when a path goes through a scalar",
+				`---
+- op: add
+  path: /jobs/0/name/first
+  value: true
+`,
+				`doc is missing path: /jobs/0/name/first: /jobs/0/name is not a map or an array`,
+			),
+			Entry("suggest values close to the value of an extended syntax predicate",
+				`---
+- op: replace
+  path: /jobs/name=upgrad-opsmgr/serial
+  value: true
+`,
+				`could not expand pointer: /jobs/name=upgrad-opsmgr/serial: nothing under /jobs has name=upgrad-opsmgr (values of name: export-installation, upgrade-opsmgr); did you mean "name=upgrade-opsmgr"?`,
+			),
+			Entry("say when nothing has the key of an extended syntax predicate",
+				`---
+- op: replace
+  path: /jobs/title=upgrade-opsmgr/serial
+  value: true
+`,
+				`could not expand pointer: /jobs/title=upgrade-opsmgr/serial: nothing under /jobs has the key "title"`,
+			),
+			Entry("name the first missing segment after an extended syntax predicate",
+				`---
+- op: replace
+  path: /jobs/name=upgrade-opsmgr/plan/get=pivnt
+  value: {get: tile}
+`,
+				`could not expand pointer: /jobs/name=upgrade-opsmgr/plan/get=pivnt: nothing under /jobs/0/plan has get=pivnt (values of get: pivnet); did you mean "get=pivnet"?`,
+			),
+		)
+
+		DescribeTable(
+			"for missing keys with non-ASCII characters",
+			func(doc, ops, message string) {
+				_, err := decodePatch(ops).Apply([]byte(doc))
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(HaveSuffix(message))
+			},
+			Entry("not suggest keys as far from a short key as its length in characters",
+				`---
+а: 1
+`,
+				`---
+- op: replace
+  path: /я
+  value: 2
+`,
+				`doc is missing key: /я: / has no key "я" (available keys: а)`,
+			),
+			Entry("not suggest keys beyond a third of the length of the missing key in characters",
+				`---
+радиус: 1
+`,
+				`---
+- op: replace
+  path: /размер
+  value: 2
+`,
+				`doc is missing key: /размер: / has no key "размер" (available keys: радиус)`,
+			),
+			Entry("suggest keys by their distance in characters",
+				`---
+größe: 1
+`,
+				`---
+- op: replace
+  path: /grösse
+  value: 2
 `,
+				`doc is missing key: /grösse: / has no key "grösse" (available keys: größe); did you mean "größe"?`,
 			),
 		)
 	})
@@ -596,9 +2276,10 @@ name:
 			value := yamlpatch.NewNode(&v)
 			Expect(patch).To(Equal(yamlpatch.Patch{
 				{
-					Op:    "add",
-					Path:  "/baz",
-					Value: value,
+					Op:     "add",
+					Path:   "/baz",
+					Value:  value,
+					Source: yamlpatch.Source{Index: 0, Line: 2, Column: 3},
 				},
 			}))
 		})
@@ -2,6 +2,8 @@ package yamlpatch
 
 import (
 	"fmt"
+	"sort"
+	"strconv"
 	"strings"
 )
 
@@ -20,7 +22,7 @@ func NewPathFinder(container Container) *PathFinder {
 }
 
 // Find expands the given path into all matching paths, returning the canonical
-// versions of those matching paths
+// versions of those matching paths in document order
 func (p *PathFinder) Find(path string) []string {
 	parts := strings.Split(path, "/")
 
@@ -41,9 +43,124 @@ func (p *PathFinder) Find(path string) []string {
 		paths = append(paths, k)
 	}
 
+	sort.Slice(paths, func(i, j int) bool {
+		return comparePaths(paths[i], paths[j]) < 0
+	})
+
 	return paths
 }
 
+// explain returns an error describing the first segment of the path that
+// matches nothing, suggesting keys or values close to the ones it names
+func (p *PathFinder) explain(path string) error {
+	routes := map[string]Container{
+		"": p.root,
+	}
+
+	for _, part := range strings.Split(path, "/")[1:] {
+		part = decodePatchKey(part)
+
+		next := find(part, routes)
+		if len(next) == 0 {
+			return unmatched(part, routes)
+		}
+
+		routes = next
+	}
+
+	return fmt.Errorf("%s matches nothing", path)
+}
+
+// unmatched describes why a segment of a path matched none of the routes
+func unmatched(part string, routes map[string]Container) error {
+	var prefixes []string
+	for prefix := range routes {
+		prefixes = append(prefixes, prefix)
+	}
+
+	sort.Slice(prefixes, func(i, j int) bool {
+		return comparePaths(prefixes[i], prefixes[j]) < 0
+	})
+
+	kv := strings.Split(part, "=")
+	if len(kv) != 2 {
+		return missingKey(routes[prefixes[0]], prefixes[0], part)
+	}
+
+	found := map[string]bool{}
+	for _, prefix := range prefixes {
+		findValues(kv[0], routes[prefix], found)
+	}
+
+	var values []string
+	for v := range found {
+		values = append(values, v)
+	}
+	sort.Strings(values)
+
+	at := prefixes[0]
+	if at == "" {
+		at = "/"
+	}
+
+	if len(values) == 0 {
+		return fmt.Errorf("nothing under %s has the key %q", at, kv[0])
+	}
+
+	var suggestion string
+	if matches := suggest(kv[1], values); len(matches) > 0 {
+		for i := range matches {
+			matches[i] = kv[0] + "=" + matches[i]
+		}
+		suggestion = didYouMean(part, matches)
+	}
+
+	return fmt.Errorf("nothing under %s has %s (values of %s: %s)%s", at, part, kv[0], listKeys(values), suggestion)
+}
+
+// findValues adds each string value of findKey within the container to found
+func findValues(findKey string, container Container, found map[string]bool) {
+	switch it := container.(type) {
+	case *nodeMap:
+		for k, v := range *it {
+			if s, ok := v.Value().(string); ok && k == findKey {
+				found[s] = true
+			}
+			findValues(findKey, v.Container(), found)
+		}
+	case *nodeSlice:
+		for _, v := range *it {
+			findValues(findKey, v.Container(), found)
+		}
+	}
+}
+
+// comparePaths orders canonical paths segment by segment, comparing array
+// indexes numerically so that paths sort in document order
+func comparePaths(a, b string) int {
+	as := strings.Split(a, "/")
+	bs := strings.Split(b, "/")
+
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		if as[i] == bs[i] {
+			continue
+		}
+
+		ai, aerr := strconv.Atoi(as[i])
+		bi, berr := strconv.Atoi(bs[i])
+		if aerr == nil && berr == nil {
+			if ai < bi {
+				return -1
+			}
+			return 1
+		}
+
+		return strings.Compare(as[i], bs[i])
+	}
+
+	return len(as) - len(bs)
+}
+
 func find(part string, routes map[string]Container) map[string]Container {
 	matches := map[string]Container{}
 
@@ -62,6 +62,12 @@ jobs:
 			Entry("return a route when given a pointer with a leaf that does not exist", "/jobs/name=job1/nonexistent", []string{"/jobs/0/nonexistent"}),
 			Entry("return a route when given a pointer with an array thingy", "/jobs/name=job1/plan/-", []string{"/jobs/0/plan/-"}),
 		)
+
+		It("returns routes in document order", func() {
+			Expect(pathfinder.Find("/jobs/get=A")).To(Equal([]string{"/jobs/0/plan/0", "/jobs/1/plan/0/aggregate/1"}))
+			Expect(pathfinder.Find("/jobs/get=A/arg=arg2")).To(Equal([]string{"/jobs/0/plan/0/args/1"}))
+		})
+
 		DescribeTable(
 			"should not",
 			func(path string) {
@@ -0,0 +1,251 @@
+package yamlpatch
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// Provenance maps each path changed by a patch to the source of the last
+// operation that changed it. A Provenance passed in ApplyOptions is filled in
+// as the patch is applied, and can be passed to several patches in turn.
+type Provenance map[OpPath]Source
+
+// provenanceObserver is an observer that fills in a Provenance, and collects
+// the comments of the operations it records when comments is set
+type provenanceObserver struct {
+	provenance Provenance
+	comments   map[Source]string
+	op         Operation
+	old        *Node
+}
+
+func (p *provenanceObserver) expanded(index int, op *Operation, err error) {
+	p.op = *op
+
+	// operations that were not decoded from a file are known by their index
+	if p.op.Source.File == "" {
+		p.op.Source.Index = index
+	}
+
+	if p.comments != nil && op.Comment != "" {
+		p.comments[p.op.Source] = op.Comment
+	}
+}
+
+func (p *provenanceObserver) beforeOp(index int, op *Operation, c Container) error {
+	p.old = lookupNode(c, op.Path).clone()
+	return nil
+}
+
+func (p *provenanceObserver) afterOp(index int, op *Operation, c Container, err error) {
+	if err != nil {
+		return
+	}
+
+	if op.Op == opMove {
+		p.removed(c, op.From)
+	}
+
+	path := concretePath(c, op.Path)
+	switch {
+	case op.Op == opRemove:
+		p.removed(c, path)
+	case op.Op == opAdd && isListElement(c, path):
+		p.shift(path, 1)
+		p.record(path)
+	default:
+		node := lookupNode(c, path)
+		if node != nil && (p.old == nil || !p.old.Equal(node)) {
+			p.record(path)
+		}
+	}
+}
+
+// record attributes the path to the current operation, replacing anything
+// recorded for paths beneath it
+func (p *provenanceObserver) record(path OpPath) {
+	p.forget(path)
+	p.provenance[path] = p.op.Source
+}
+
+// removed forgets the path, and renumbers the elements that followed it when
+// it was an element of a list
+func (p *provenanceObserver) removed(c Container, path OpPath) {
+	p.forget(path)
+
+	if isListElement(c, path) {
+		p.shift(path, -1)
+	}
+}
+
+// forget removes the path and any paths beneath it
+func (p *provenanceObserver) forget(path OpPath) {
+	prefix := string(path) + "/"
+	for k := range p.provenance {
+		if k == path || strings.HasPrefix(string(k), prefix) {
+			delete(p.provenance, k)
+		}
+	}
+}
+
+// shift renumbers the paths of the list elements at or after the element at
+// path by delta, as when an element is inserted into or removed from a list
+func (p *provenanceObserver) shift(path OpPath, delta int) {
+	parts, key, _ := path.Decompose()
+	at, err := strconv.Atoi(key)
+	if err != nil {
+		return
+	}
+
+	prefix := "/" + strings.Join(parts, "/")
+	if len(parts) == 0 {
+		prefix = ""
+	}
+
+	shifted := make(Provenance)
+	for k, source := range p.provenance {
+		rest := strings.TrimPrefix(string(k), prefix+"/")
+		if rest == string(k) {
+			continue
+		}
+
+		segment := rest
+		var tail string
+		if i := strings.Index(rest, "/"); i >= 0 {
+			segment, tail = rest[:i], rest[i:]
+		}
+
+		i, err := strconv.Atoi(segment)
+		if err != nil || i < at {
+			continue
+		}
+
+		delete(p.provenance, k)
+		shifted[OpPath(fmt.Sprintf("%s/%d%s", prefix, i+delta, tail))] = source
+	}
+
+	for k, source := range shifted {
+		p.provenance[k] = source
+	}
+}
+
+// isListElement returns whether the path refers to an element of a list
+func isListElement(c Container, path OpPath) bool {
+	parts, _, err := path.Decompose()
+	if err != nil {
+		return false
+	}
+
+	parent := c
+	if len(parts) > 0 {
+		node := lookupNode(c, OpPath("/"+strings.Join(parts, "/")))
+		if node == nil {
+			return false
+		}
+		parent = node.Container()
+	}
+
+	_, ok := parent.(*nodeSlice)
+	return ok
+}
+
+// Annotate returns the document with a comment on each node whose path is in
+// the provenance, giving the source of the operation that last changed it
+func (p Provenance) Annotate(doc []byte) ([]byte, error) {
+	comments := make(map[OpPath]string, len(p))
+	for path, source := range p {
+		comments[path] = source.String()
+	}
+
+	return annotate(doc, comments)
+}
+
+// annotations returns the comment to annotate each recorded path with
+func (p *provenanceObserver) annotations() map[OpPath]string {
+	annotations := make(map[OpPath]string, len(p.provenance))
+	for path, source := range p.provenance {
+		origin := fmt.Sprintf("operation %d", source.Index)
+		if source.File != "" {
+			origin = fmt.Sprintf("%s#%d", source.File, source.Index)
+		}
+
+		if comment, ok := p.comments[source]; ok {
+			origin = fmt.Sprintf("%s: %s", origin, strings.Join(strings.Fields(comment), " "))
+		}
+
+		annotations[path] = "yaml-patch: " + origin
+	}
+
+	return annotations
+}
+
+// annotate returns the document with the given comment added to the node at
+// each path
+func annotate(doc []byte, comments map[OpPath]string) ([]byte, error) {
+	var root yamlv3.Node
+	err := yamlv3.Unmarshal(doc, &root)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(root.Content) > 0 {
+		annotateNode(root.Content[0], "", comments)
+	}
+
+	var buf bytes.Buffer
+	enc := yamlv3.NewEncoder(&buf)
+	enc.SetIndent(2)
+
+	err = enc.Encode(&root)
+	if err != nil {
+		return nil, err
+	}
+
+	err = enc.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// annotateNode adds comments to the children of the node at path. Comments
+// on scalars follow the value, while comments on maps and lists follow their
+// key or precede the list item.
+func annotateNode(n *yamlv3.Node, path string, comments map[OpPath]string) {
+	switch n.Kind {
+	case yamlv3.MappingNode:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			k, v := n.Content[i], n.Content[i+1]
+			p := fmt.Sprintf("%s/%s", path, encodePatchKey(k.Value))
+
+			if comment, ok := comments[OpPath(p)]; ok {
+				if v.Kind == yamlv3.ScalarNode {
+					v.LineComment = comment
+				} else {
+					k.LineComment = comment
+				}
+			}
+
+			annotateNode(v, p, comments)
+		}
+	case yamlv3.SequenceNode:
+		for i, v := range n.Content {
+			p := fmt.Sprintf("%s/%d", path, i)
+
+			if comment, ok := comments[OpPath(p)]; ok {
+				if v.Kind == yamlv3.ScalarNode {
+					v.LineComment = comment
+				} else {
+					v.HeadComment = comment
+				}
+			}
+
+			annotateNode(v, p, comments)
+		}
+	}
+}
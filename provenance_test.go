@@ -0,0 +1,190 @@
+package yamlpatch_test
+
+import (
+	yamlpatch "github.com/krishicks/yaml-patch"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Provenance", func() {
+	var (
+		loader     memLoader
+		provenance yamlpatch.Provenance
+		doc        []byte
+	)
+
+	BeforeEach(func() {
+		loader = memLoader{
+			files: map[string]string{
+				"/ops/scale.yml": `---
+- op: replace
+  path: /jobs/name=web/instances
+  value: 3
+
+- op: test
+  path: /jobs/name=web/instances
+  value: 3
+`,
+				"/ops/jobs.yml": `---
+- op: add
+  path: /jobs/0
+  value: {name: db, instances: 1}
+- op: replace
+  path: /jobs/name=web/instances
+  value: 3
+- op: add
+  path: /jobs/name=worker/env
+  value: {QUEUE: jobs}
+- op: remove
+  path: /stale
+`,
+			},
+		}
+
+		provenance = yamlpatch.Provenance{}
+		doc = []byte(`---
+jobs:
+- name: web
+  instances: 1
+- name: worker
+  instances: 2
+stale: true
+`)
+	})
+
+	apply := func(paths ...string) []byte {
+		for _, path := range paths {
+			patch, err := yamlpatch.DecodePatchFile(loader, path)
+			Expect(err).NotTo(HaveOccurred())
+
+			doc, err = patch.ApplyWithOptions(doc, yamlpatch.ApplyOptions{Provenance: provenance})
+			Expect(err).NotTo(HaveOccurred())
+		}
+
+		return doc
+	}
+
+	It("records the source of the operation that changed each path", func() {
+		apply("/ops/scale.yml")
+
+		Expect(provenance).To(Equal(yamlpatch.Provenance{
+			"/jobs/0/instances": {File: "/ops/scale.yml", Index: 0, Line: 2, Column: 3},
+		}))
+	})
+
+	It("attributes each path to the last operation that changed it", func() {
+		apply("/ops/scale.yml", "/ops/jobs.yml")
+
+		Expect(provenance).To(Equal(yamlpatch.Provenance{
+			"/jobs/0":           {File: "/ops/jobs.yml", Index: 0, Line: 2, Column: 3},
+			"/jobs/1/instances": {File: "/ops/scale.yml", Index: 0, Line: 2, Column: 3},
+			"/jobs/2/env":       {File: "/ops/jobs.yml", Index: 2, Line: 8, Column: 3},
+		}))
+	})
+
+	It("forgets paths beneath a path that is replaced", func() {
+		loader.files["/ops/reset.yml"] = `---
+- op: replace
+  path: /jobs/0
+  value: {name: web, instances: 5}
+`
+		apply("/ops/scale.yml", "/ops/reset.yml")
+
+		Expect(provenance).To(Equal(yamlpatch.Provenance{
+			"/jobs/0": {File: "/ops/reset.yml", Index: 0, Line: 2, Column: 3},
+		}))
+	})
+
+	It("records the paths changed by the operations nested in a foreach", func() {
+		loader.files["/ops/each.yml"] = `---
+- op: foreach
+  path: /jobs/name=web
+  ops:
+  - op: add
+    path: /serial
+    value: true
+`
+		apply("/ops/each.yml")
+
+		Expect(provenance).To(Equal(yamlpatch.Provenance{
+			"/jobs/0/serial": {File: "/ops/each.yml", Index: 0, Line: 2, Column: 3},
+		}))
+	})
+
+	Describe("Annotate", func() {
+		It("comments each changed node with its source", func() {
+			bs, err := provenance.Annotate(apply("/ops/scale.yml", "/ops/jobs.yml"))
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(string(bs)).To(Equal(`jobs:
+  # /ops/jobs.yml:2
+  - instances: 1
+    name: db
+  - instances: 3 # /ops/scale.yml:2
+    name: web
+  - env: # /ops/jobs.yml:8
+      QUEUE: jobs
+    instances: 2
+    name: worker
+`))
+		})
+	})
+
+	Describe("with Annotate", func() {
+		It("comments each node written with the source and comment of its operation", func() {
+			loader.files["/ops/scale.yml"] = `---
+- op: replace
+  path: /jobs/name=web/instances
+  value: 3
+  comment: scale up for launch
+- op: add
+  path: /jobs/name=worker/env
+  value: {QUEUE: jobs}
+`
+			patch, err := yamlpatch.DecodePatchFile(loader, "/ops/scale.yml")
+			Expect(err).NotTo(HaveOccurred())
+
+			bs, err := patch.ApplyWithOptions(doc, yamlpatch.ApplyOptions{Annotate: true})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(string(bs)).To(Equal(`jobs:
+  - instances: 3 # yaml-patch: /ops/scale.yml#0: scale up for launch
+    name: web
+  - env: # yaml-patch: /ops/scale.yml#1
+      QUEUE: jobs
+    instances: 2
+    name: worker
+stale: true
+`))
+		})
+
+		It("identifies operations that were not decoded from a file by their index", func() {
+			patch, err := yamlpatch.DecodePatch([]byte(`---
+- op: test
+  path: /stale
+  value: true
+- op: add
+  path: /jobs/0
+  value: {name: db}
+  comment: |
+    add a database
+    before the web job
+`))
+			Expect(err).NotTo(HaveOccurred())
+
+			bs, err := patch.ApplyWithOptions(doc, yamlpatch.ApplyOptions{Annotate: true})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(string(bs)).To(Equal(`jobs:
+  # yaml-patch: operation 1: add a database before the web job
+  - name: db
+  - instances: 1
+    name: web
+  - instances: 2
+    name: worker
+stale: true
+`))
+		})
+	})
+})
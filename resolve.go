@@ -0,0 +1,139 @@
+package yamlpatch
+
+import (
+	"encoding/json"
+	"fmt"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Resolve returns a patch equivalent to p when applied to the document that
+// uses only RFC 6902 operations and plain RFC 6901 pointers, so that it can be
+// used by other tools. Each operation is applied to the document in turn:
+//
+//   - paths and froms that use extended syntax are expanded into an operation
+//     for each path they match
+//   - values taken from the document are replaced by the values themselves
+//   - the operations in a foreach are resolved at each node it matches
+//   - a rename becomes a move, and other extended operations become an add,
+//     replace or remove of the value they produce
+//   - tests other than test itself are checked and left out
+//
+// An error is returned if any operation fails to apply.
+func (p Patch) Resolve(doc []byte) (Patch, error) {
+	var iface interface{}
+	err := yaml.Unmarshal(doc, &iface)
+	if err != nil {
+		return nil, fmt.Errorf("failed unmarshaling doc: %s\n\n%s", string(doc), err)
+	}
+
+	return p.resolve(NewNode(&iface).Container(), "")
+}
+
+// resolve resolves the patch against the container, whose path in the
+// document is prefix
+func (p Patch) resolve(c Container, prefix OpPath) (Patch, error) {
+	var resolved Patch
+
+	for i, op := range p {
+		ops, err := op.expand(c)
+		if err != nil {
+			return nil, &ApplyError{Index: i, Op: op.Op, Path: op.Path, Source: op.Source, Err: err}
+		}
+
+		for _, concrete := range ops {
+			rops, err := concrete.resolve(c, prefix)
+			if err != nil {
+				return nil, &ApplyError{Index: i, Op: concrete.Op, Path: concrete.Path, Source: concrete.Source, Err: err}
+			}
+
+			resolved = append(resolved, rops...)
+		}
+	}
+
+	return resolved, nil
+}
+
+// resolve performs the operation, which has already been expanded, and
+// returns the RFC 6902 operations equivalent to it
+func (o *Operation) resolve(c Container, prefix OpPath) (Patch, error) {
+	op, err := o.resolveValue(c)
+	if err != nil {
+		return nil, fmt.Errorf("yamlpatch %s operation does not apply: %s", o.Op, err)
+	}
+
+	if op.Op == opForeach {
+		node := lookupNode(c, op.Path)
+		if node == nil || node.Container() == nil {
+			// the foreach fails without performing any of its operations
+			return nil, op.Perform(c)
+		}
+
+		return op.Ops.resolve(node.Container(), prefix+op.Path)
+	}
+
+	path := concretePath(c, op.Path)
+	before := lookupNode(c, path).clone()
+
+	err = op.Perform(c)
+	if err != nil {
+		return nil, err
+	}
+
+	plain := Operation{Op: op.Op, Path: prefix + op.Path, Source: op.Source}
+
+	switch op.Op {
+	case opAdd, opReplace, opTest:
+		plain.Value = op.Value
+	case opRemove:
+	case opMove, opCopy:
+		plain.From = prefix + op.From
+	case opRename:
+		plain.Op = opMove
+		plain.From = plain.Path
+		plain.Path = OpPath(fmt.Sprintf("%s/%s", prefix+OpPath(parentPath(op.Path)), encodePatchKey(op.To)))
+	case opTestAbsent, opTestPresent, opTestType, opTestRegex, opTestRange, opTestLength, opTestContains:
+		return nil, nil
+	default:
+		after := lookupNode(c, path).clone()
+		plain.Path = prefix + path
+
+		switch {
+		case after == nil && before == nil:
+			return nil, nil
+		case after == nil:
+			plain.Op = opRemove
+		case before == nil:
+			plain.Op = opAdd
+			plain.Value = after
+		case before.Equal(after):
+			return nil, nil
+		default:
+			plain.Op = opReplace
+			plain.Value = after
+		}
+	}
+
+	return Patch{plain}, nil
+}
+
+// MarshalJSON implements json.Marshaler, encoding the patch as an RFC 6902
+// JSON patch
+func (p Patch) MarshalJSON() ([]byte, error) {
+	bs, err := yaml.Marshal([]Operation(p))
+	if err != nil {
+		return nil, err
+	}
+
+	var ops interface{}
+	err = yaml.Unmarshal(bs, &ops)
+	if err != nil {
+		return nil, err
+	}
+
+	if ops == nil {
+		ops = []interface{}{}
+	}
+
+	return json.Marshal(jsonValue(ops))
+}
@@ -0,0 +1,185 @@
+package yamlpatch_test
+
+import (
+	"encoding/json"
+
+	yaml "gopkg.in/yaml.v2"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Resolve", func() {
+	doc := []byte(`---
+defaults:
+  timeout: 5m
+jobs:
+- name: job1
+  serial: false
+  plan:
+  - get: A
+  - get: B
+- name: job2
+  plan:
+  - get: A
+`)
+
+	DescribeTable(
+		"returns an equivalent patch with only plain pointers",
+		func(ops, resolved string) {
+			patch := decodePatch(ops)
+
+			actual, err := patch.Resolve(doc)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(yaml.Marshal(actual)).To(MatchYAML(resolved))
+
+			expected, err := patch.Apply(doc)
+			Expect(err).NotTo(HaveOccurred())
+
+			actualBytes, err := actual.Apply(doc)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(actualBytes).To(MatchYAML(expected))
+		},
+		Entry("expanding extended syntax into each path it matches",
+			`---
+- op: add
+  path: /jobs/get=A/params
+  value: {trigger: true}
+- op: replace
+  path: /jobs/name=job1/serial
+  value: true
+`,
+			`---
+- op: add
+  path: /jobs/0/plan/0/params
+  value: {trigger: true}
+- op: add
+  path: /jobs/1/plan/0/params
+  value: {trigger: true}
+- op: replace
+  path: /jobs/0/serial
+  value: true
+`,
+		),
+		Entry("expanding from and resolving values from the document",
+			`---
+- op: copy
+  from: /jobs/name=job1/plan/get=B
+  path: /jobs/name=job2/first_get
+- op: add
+  path: /jobs/name=job2/timeout
+  valueFrom: /defaults/timeout
+- op: add
+  path: /jobs/name=job1/settings
+  resolveRefs: true
+  value: {timeout: {$ref: /defaults/timeout}}
+`,
+			`---
+- op: copy
+  from: /jobs/0/plan/1
+  path: /jobs/1/first_get
+- op: add
+  path: /jobs/1/timeout
+  value: 5m
+- op: add
+  path: /jobs/0/settings
+  value: {timeout: 5m}
+`,
+		),
+		Entry("resolving the operations in a foreach at each node",
+			`---
+- op: foreach
+  path: /jobs/get=A
+  ops:
+  - op: add
+    path: /version
+    value: every
+  - op: add
+    path: /params
+    valueFrom: /get
+`,
+			`---
+- op: add
+  path: /jobs/0/plan/0/version
+  value: every
+- op: add
+  path: /jobs/0/plan/0/params
+  value: A
+- op: add
+  path: /jobs/1/plan/0/version
+  value: every
+- op: add
+  path: /jobs/1/plan/0/params
+  value: A
+`,
+		),
+		Entry("turning extended operations into plain ones",
+			`---
+- op: rename
+  path: /jobs/name=job1/serial
+  to: serial_groups
+- op: extend
+  path: /jobs/name=job2/plan
+  value: [{get: C}]
+- op: merge
+  path: /defaults
+  value: {retries: 3}
+- op: merge
+  path: /settings
+  value: {retries: 3}
+- op: test-present
+  path: /jobs/name=job2
+- op: add-unique
+  path: /jobs/name=job2/plan
+  value: {get: A}
+`,
+			`---
+- op: move
+  from: /jobs/0/serial
+  path: /jobs/0/serial_groups
+- op: replace
+  path: /jobs/1/plan
+  value: [{get: A}, {get: C}]
+- op: replace
+  path: /defaults
+  value: {timeout: 5m, retries: 3}
+- op: add
+  path: /settings
+  value: {retries: 3}
+`,
+		),
+	)
+
+	It("returns an error when an operation fails", func() {
+		_, err := decodePatch(`---
+- op: replace
+  path: /jobs/name=job3/serial
+  value: true
+`).Resolve(doc)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(HavePrefix("operation 0 (replace /jobs/name=job3/serial) at line 2: could not expand pointer"))
+	})
+
+	It("encodes as an RFC 6902 JSON patch", func() {
+		resolved, err := decodePatch(`---
+- op: replace
+  path: /jobs/name=job1/serial
+  value: true
+- op: remove
+  path: /defaults
+- op: add
+  path: /labels
+  value: {1: one}
+`).Resolve(doc)
+		Expect(err).NotTo(HaveOccurred())
+
+		bs, err := json.Marshal(resolved)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(bs).To(MatchJSON(`[
+  {"op": "replace", "path": "/jobs/0/serial", "value": true},
+  {"op": "remove", "path": "/defaults"},
+  {"op": "add", "path": "/labels", "value": {"1": "one"}}
+]`))
+	})
+})
@@ -0,0 +1,228 @@
+package yamlpatch
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// toFloat returns the value as a float64 if it is one of the numeric types
+// produced by unmarshaling YAML
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+
+	return 0, false
+}
+
+// toInt returns the value as an int64 if it is one of the integer types
+// produced by unmarshaling YAML
+func toInt(v interface{}) (int64, bool) {
+	switch n := v.(type) {
+	case int:
+		return int64(n), true
+	case int64:
+		return n, true
+	case uint64:
+		if n <= math.MaxInt64 {
+			return int64(n), true
+		}
+	}
+
+	return 0, false
+}
+
+// calculate applies the arithmetic op to the numbers a and b. The result is an
+// int when both numbers are integers, and a float64 otherwise. An integer
+// result that overflows an int is an error.
+func calculate(op Op, a, b interface{}) (interface{}, error) {
+	ai, aok := toInt(a)
+	bi, bok := toInt(b)
+	if aok && bok {
+		switch op {
+		case opIncrement:
+			if (bi > 0 && ai > math.MaxInt64-bi) || (bi < 0 && ai < math.MinInt64-bi) {
+				return nil, overflowError(op, ai, bi)
+			}
+			return intResult(op, ai, bi, ai+bi)
+		case opDecrement:
+			if (bi < 0 && ai > math.MaxInt64+bi) || (bi > 0 && ai < math.MinInt64+bi) {
+				return nil, overflowError(op, ai, bi)
+			}
+			return intResult(op, ai, bi, ai-bi)
+		case opMultiply:
+			r := ai * bi
+			if (ai != 0 && r/ai != bi) || (ai == -1 && bi == math.MinInt64) || (bi == -1 && ai == math.MinInt64) {
+				return nil, overflowError(op, ai, bi)
+			}
+			return intResult(op, ai, bi, r)
+		case opMin:
+			if bi < ai {
+				return int(bi), nil
+			}
+			return int(ai), nil
+		case opMax:
+			if bi > ai {
+				return int(bi), nil
+			}
+			return int(ai), nil
+		}
+	}
+
+	af, aok := toFloat(a)
+	bf, bok := toFloat(b)
+	if !aok || !bok {
+		return nil, fmt.Errorf("not numeric: %v, %v", a, b)
+	}
+
+	switch op {
+	case opIncrement:
+		return af + bf, nil
+	case opDecrement:
+		return af - bf, nil
+	case opMultiply:
+		return af * bf, nil
+	case opMin:
+		return math.Min(af, bf), nil
+	case opMax:
+		return math.Max(af, bf), nil
+	}
+
+	return nil, fmt.Errorf("Unexpected arithmetic op: %s", op)
+}
+
+// intResult returns the result of an integer op as an int, or an error if it
+// does not fit in one
+func intResult(op Op, a, b, r int64) (interface{}, error) {
+	if int64(int(r)) != r {
+		return nil, overflowError(op, a, b)
+	}
+
+	return int(r), nil
+}
+
+func overflowError(op Op, a, b int64) error {
+	return fmt.Errorf("%s of %d by %d overflows an integer", op, a, b)
+}
+
+// compareValues orders two scalar values, returning -1, 0 or 1. Numbers are
+// compared numerically, strings lexically and false sorts before true. Nil
+// sorts before everything else, and values of differing types are compared
+// by their string representations.
+func compareValues(a, b interface{}) int {
+	if a == nil || b == nil {
+		switch {
+		case a == nil && b == nil:
+			return 0
+		case a == nil:
+			return -1
+		default:
+			return 1
+		}
+	}
+
+	if af, ok := toFloat(a); ok {
+		if bf, ok := toFloat(b); ok {
+			switch {
+			case af < bf:
+				return -1
+			case af > bf:
+				return 1
+			default:
+				return 0
+			}
+		}
+	}
+
+	if ab, ok := a.(bool); ok {
+		if bb, ok := b.(bool); ok {
+			switch {
+			case ab == bb:
+				return 0
+			case !ab:
+				return -1
+			default:
+				return 1
+			}
+		}
+	}
+
+	return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+}
+
+// marshalValue marshals a value unmarshaled from YAML. yaml.v2 writes a float
+// with an integral value without a fractional part, e.g. 3 for 3.0, which
+// reads back as an int, so such floats are marshaled as placeholders that
+// are then replaced with the float written with a fractional part.
+func marshalValue(v interface{}) ([]byte, error) {
+	bs, err := yaml.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	// the placeholders must not appear anywhere else in the document
+	prefix := "yamlpatch-float"
+	for bytes.Contains(bs, []byte(prefix)) {
+		prefix += "-"
+	}
+
+	floats := map[string]string{}
+	marked := markFloats(v, prefix, floats)
+	if len(floats) == 0 {
+		return bs, nil
+	}
+
+	bs, err = yaml.Marshal(marked)
+	if err != nil {
+		return nil, err
+	}
+
+	for placeholder, text := range floats {
+		bs = bytes.Replace(bs, []byte(placeholder), []byte(text), 1)
+	}
+
+	return bs, nil
+}
+
+// markFloats returns a copy of the value with each float that yaml.v2 would
+// write without a fractional part replaced by a placeholder, recording the
+// text to replace each placeholder with in floats
+func markFloats(v interface{}, prefix string, floats map[string]string) interface{} {
+	switch vt := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[interface{}]interface{}, len(vt))
+		for k, e := range vt {
+			m[k] = markFloats(e, prefix, floats)
+		}
+		return m
+	case []interface{}:
+		ary := make([]interface{}, len(vt))
+		for i, e := range vt {
+			ary[i] = markFloats(e, prefix, floats)
+		}
+		return ary
+	case float64:
+		text := strconv.FormatFloat(vt, 'g', -1, 64)
+		if math.IsInf(vt, 0) || math.IsNaN(vt) || strings.ContainsAny(text, ".e") {
+			return vt
+		}
+
+		placeholder := fmt.Sprintf("%s-%d-", prefix, len(floats))
+		floats[placeholder] = text + ".0"
+		return placeholder
+	}
+
+	return v
+}
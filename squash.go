@@ -0,0 +1,228 @@
+package yamlpatch
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Compose returns a single patch equivalent to applying each of the patches
+// in turn, squashed as with Squash
+func Compose(patches ...Patch) Patch {
+	var p Patch
+	for _, patch := range patches {
+		p = append(p, patch...)
+	}
+
+	return p.Squash()
+}
+
+// Squash returns an equivalent patch in which sequential add, replace and
+// remove operations on the same path are merged. An add or replace followed
+// by a replace becomes a single operation with the later value, an insert
+// into an array followed by a remove of the same index is dropped, a remove
+// followed by an add becomes a replace, writes beneath a value that was just
+// added or replaced are made to the value itself, and writes that are
+// overwritten by a later write to an ancestor are dropped.
+//
+// Squashing is conservative: any other operation, or one that uses extended
+// syntax, reads from another path or refers to the document, is left where it
+// is and nothing is merged across it. An add to a key followed by a remove of
+// it is kept, since the add may have overwritten a value that the remove then
+// removes. The patch is assumed to apply cleanly, so a squashed patch can
+// succeed on documents where the original would fail.
+func (p Patch) Squash() Patch {
+	var out Patch
+	for _, op := range p {
+		out = squashInto(out, op)
+	}
+
+	return out
+}
+
+// squashInto appends the operation to the squashed patch, merging it with
+// the last operation it overlaps if they can be merged
+func squashInto(out Patch, op Operation) Patch {
+	if !squashable(op) {
+		return append(out, op)
+	}
+
+	for j := len(out) - 1; j >= 0; j-- {
+		prev := out[j]
+		if !squashable(prev) {
+			break
+		}
+
+		switch relatePaths(prev.Path, op.Path) {
+		case pathsIndependent:
+			continue
+		case pathsOverlap:
+			merged, ok := mergeOps(prev, op)
+			if !ok {
+				return append(out, op)
+			}
+
+			// the operations after prev are independent of op, so op can be
+			// moved before them to take prev's place
+			squashed := append(Patch{}, out[:j]...)
+			for _, m := range merged {
+				squashed = squashInto(squashed, m)
+			}
+
+			return append(squashed, out[j+1:]...)
+		}
+
+		break
+	}
+
+	return append(out, op)
+}
+
+// squashable returns whether the operation only writes a known value to a
+// plain path, and so can be merged with others
+func squashable(op Operation) bool {
+	switch op.Op {
+	case opAdd, opReplace, opRemove:
+	default:
+		return false
+	}
+
+	return !op.Path.ContainsExtendedSyntax() &&
+		op.From == "" &&
+		op.ValueFrom == "" &&
+		op.Include == "" &&
+		!op.ResolveRefs
+}
+
+type pathRelation int
+
+const (
+	pathsIndependent pathRelation = iota
+	// pathsOverlap is when one path is the other or one of its ancestors
+	pathsOverlap
+	// pathsSiblings is when the paths diverge at indexes of the same array,
+	// so that a write to one can move the other
+	pathsSiblings
+)
+
+func relatePaths(a, b OpPath) pathRelation {
+	as, bs := pathSegments(a), pathSegments(b)
+
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		if as[i] == bs[i] {
+			continue
+		}
+
+		if isIndex(as[i]) && isIndex(bs[i]) {
+			return pathsSiblings
+		}
+
+		return pathsIndependent
+	}
+
+	return pathsOverlap
+}
+
+func pathSegments(path OpPath) []string {
+	if path == "" || path == "/" {
+		return nil
+	}
+
+	return strings.Split(string(path), "/")[1:]
+}
+
+// isIndex returns whether the segment could be an index into an array
+func isIndex(segment string) bool {
+	if segment == "-" {
+		return true
+	}
+
+	_, err := strconv.Atoi(segment)
+	return err == nil
+}
+
+// mergeOps returns the operations equivalent to prev followed by op, where
+// one's path is the other or one of its ancestors
+func mergeOps(prev, op Operation) ([]Operation, bool) {
+	prevSegments, segments := pathSegments(prev.Path), pathSegments(op.Path)
+
+	switch {
+	case len(prevSegments) == len(segments):
+		return mergeSamePath(prev, op, lastSegment(segments))
+	case len(prevSegments) < len(segments):
+		return mergeIntoValue(prev, op, segments[len(prevSegments):])
+	}
+
+	// op overwrites the value that prev wrote beneath, unless it inserts
+	// into an array, moving that value along
+	if op.Op == opAdd && isIndex(lastSegment(segments)) {
+		return nil, false
+	}
+
+	return []Operation{op}, true
+}
+
+func lastSegment(segments []string) string {
+	if len(segments) == 0 {
+		return ""
+	}
+
+	return segments[len(segments)-1]
+}
+
+// mergeSamePath merges two operations on the same path. Adds to a path that
+// may be an array index insert rather than overwrite, so are only merged
+// with a later replace or remove. An add followed by a remove is only dropped
+// for an array index, since an add to a key may overwrite a value that the
+// remove then removes.
+func mergeSamePath(prev, op Operation, last string) ([]Operation, bool) {
+	if last == "-" {
+		return nil, false
+	}
+
+	merged := op
+
+	switch {
+	case prev.Op == opAdd && op.Op == opReplace:
+		merged.Op = opAdd
+	case prev.Op == opAdd && op.Op == opRemove && isIndex(last):
+		return nil, true
+	case prev.Op == opAdd && op.Op == opAdd && !isIndex(last):
+	case prev.Op == opReplace && op.Op == opReplace:
+	case prev.Op == opReplace && op.Op == opRemove:
+	case prev.Op == opReplace && op.Op == opAdd && !isIndex(last):
+		merged.Op = opReplace
+	case prev.Op == opRemove && op.Op == opAdd:
+		merged.Op = opReplace
+	default:
+		return nil, false
+	}
+
+	return []Operation{merged}, true
+}
+
+// mergeIntoValue performs op on the value written by prev, where rest is the
+// part of op's path beneath prev's
+func mergeIntoValue(prev, op Operation, rest []string) ([]Operation, bool) {
+	if prev.Op == opRemove {
+		return nil, false
+	}
+
+	node := prev.Value.clone()
+	if node == nil || node.Container() == nil {
+		return nil, false
+	}
+
+	relative := op
+	relative.Path = OpPath("/" + strings.Join(rest, "/"))
+
+	err := relative.Perform(node.Container())
+	if err != nil {
+		return nil, false
+	}
+
+	val := node.Value()
+	merged := prev
+	merged.Value = NewNode(&val)
+
+	return []Operation{merged}, true
+}
@@ -0,0 +1,307 @@
+package yamlpatch_test
+
+import (
+	yamlpatch "github.com/krishicks/yaml-patch"
+	yaml "gopkg.in/yaml.v2"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Squash", func() {
+	docs := []string{
+		`---
+name: web
+instances: 1
+jobs:
+- name: job1
+  plan: []
+- name: job2
+  plan: []
+`,
+		`---
+name: worker
+instances: 2
+env:
+  QUEUE: default
+jobs:
+- name: job1
+  plan:
+  - get: A
+`,
+	}
+
+	DescribeTable(
+		"merges operations into an equivalent patch",
+		func(ops, squashed string) {
+			patch := decodePatch(ops)
+
+			actual := patch.Squash()
+			Expect(yaml.Marshal(actual)).To(MatchYAML(squashed))
+
+			for _, doc := range docs {
+				expected, err := patch.Apply([]byte(doc))
+				Expect(err).NotTo(HaveOccurred())
+
+				actualBytes, err := actual.Apply([]byte(doc))
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(actualBytes).To(MatchYAML(expected))
+			}
+		},
+		Entry("collapsing an add followed by a replace",
+			`---
+- op: add
+  path: /tier
+  value: frontend
+- op: replace
+  path: /tier
+  value: backend
+`,
+			`---
+- op: add
+  path: /tier
+  value: backend
+`,
+		),
+		Entry("dropping an insert into an array followed by a remove",
+			`---
+- op: add
+  path: /jobs/0
+  value: {name: job0}
+- op: replace
+  path: /instances
+  value: 3
+- op: remove
+  path: /jobs/0
+`,
+			`---
+- op: replace
+  path: /instances
+  value: 3
+`,
+		),
+		Entry("keeping an add to a key followed by a remove, since the key may have existed",
+			`---
+- op: add
+  path: /name
+  value: db
+- op: remove
+  path: /name
+`,
+			`---
+- op: add
+  path: /name
+  value: db
+- op: remove
+  path: /name
+`,
+		),
+		Entry("keeping the last of several writes to the same path",
+			`---
+- op: replace
+  path: /instances
+  value: 3
+- op: replace
+  path: /instances
+  value: 4
+- op: add
+  path: /instances
+  value: 5
+`,
+			`---
+- op: replace
+  path: /instances
+  value: 5
+`,
+		),
+		Entry("turning a remove followed by an add into a replace",
+			`---
+- op: remove
+  path: /name
+- op: add
+  path: /name
+  value: db
+`,
+			`---
+- op: replace
+  path: /name
+  value: db
+`,
+		),
+		Entry("merging writes beneath a value that was just added",
+			`---
+- op: add
+  path: /labels
+  value: {team: core}
+- op: add
+  path: /labels/tier
+  value: web
+- op: replace
+  path: /labels/team
+  value: platform
+- op: add
+  path: /labels/owners
+  value: [alice]
+- op: add
+  path: /labels/owners/-
+  value: bob
+`,
+			`---
+- op: add
+  path: /labels
+  value: {team: platform, tier: web, owners: [alice, bob]}
+`,
+		),
+		Entry("dropping writes overwritten by a later write to an ancestor",
+			`---
+- op: add
+  path: /jobs/0/serial
+  value: true
+- op: replace
+  path: /jobs/0/name
+  value: build
+- op: replace
+  path: /jobs/0
+  value: {name: deploy, plan: []}
+`,
+			`---
+- op: replace
+  path: /jobs/0
+  value: {name: deploy, plan: []}
+`,
+		),
+		Entry("merging across writes to unrelated paths",
+			`---
+- op: add
+  path: /tier
+  value: frontend
+- op: replace
+  path: /instances
+  value: 3
+- op: replace
+  path: /tier
+  value: backend
+`,
+			`---
+- op: add
+  path: /tier
+  value: backend
+- op: replace
+  path: /instances
+  value: 3
+`,
+		),
+		Entry("not merging repeated inserts into an array",
+			`---
+- op: add
+  path: /jobs/0
+  value: {name: job0}
+- op: add
+  path: /jobs/0
+  value: {name: job00}
+`,
+			`---
+- op: add
+  path: /jobs/0
+  value: {name: job0}
+- op: add
+  path: /jobs/0
+  value: {name: job00}
+`,
+		),
+		Entry("not merging across writes to other elements of the same array",
+			`---
+- op: add
+  path: /jobs/0
+  value: {name: job0}
+- op: replace
+  path: /jobs/1
+  value: {name: job1}
+- op: replace
+  path: /jobs/0
+  value: {name: job2}
+`,
+			`---
+- op: add
+  path: /jobs/0
+  value: {name: job0}
+- op: replace
+  path: /jobs/1
+  value: {name: job1}
+- op: replace
+  path: /jobs/0
+  value: {name: job2}
+`,
+		),
+		Entry("not merging across operations that are not plain writes",
+			`---
+- op: add
+  path: /tier
+  value: frontend
+- op: copy
+  from: /tier
+  path: /original_tier
+- op: replace
+  path: /tier
+  value: backend
+- op: replace
+  path: /jobs/name=job1/plan
+  value: []
+- op: replace
+  path: /jobs/name=job1/plan
+  value: [get: B]
+`,
+			`---
+- op: add
+  path: /tier
+  value: frontend
+- op: copy
+  from: /tier
+  path: /original_tier
+- op: replace
+  path: /tier
+  value: backend
+- op: replace
+  path: /jobs/name=job1/plan
+  value: []
+- op: replace
+  path: /jobs/name=job1/plan
+  value: [get: B]
+`,
+		),
+	)
+
+	Describe("Compose", func() {
+		It("squashes the patches as if they were one", func() {
+			composed := yamlpatch.Compose(
+				decodePatch(`---
+- op: add
+  path: /tier
+  value: frontend
+- op: replace
+  path: /instances
+  value: 3
+`),
+				decodePatch(`---
+- op: replace
+  path: /tier
+  value: backend
+`),
+				decodePatch(`---
+- op: remove
+  path: /instances
+`),
+			)
+
+			Expect(yaml.Marshal(composed)).To(MatchYAML(`---
+- op: add
+  path: /tier
+  value: backend
+- op: remove
+  path: /instances
+`))
+		})
+	})
+})
@@ -0,0 +1,130 @@
+package yamlpatch
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// maxListedKeys is the number of available keys listed when a key is missing
+const maxListedKeys = 10
+
+// missingKey returns an error describing why key could not be found in the
+// container at path, listing the keys that are available and suggesting any
+// that are close to it
+func missingKey(c Container, path, key string) error {
+	if path == "" {
+		path = "/"
+	}
+
+	switch it := c.(type) {
+	case *nodeMap:
+		var keys []string
+		for k := range *it {
+			keys = append(keys, fmt.Sprintf("%v", k))
+		}
+		sort.Strings(keys)
+
+		return fmt.Errorf("%s has no key %q (available keys: %s)%s", path, key, listKeys(keys), didYouMean(key, keys))
+	case *nodeSlice:
+		return fmt.Errorf("%s has no index %s (it has %d elements)", path, key, len(*it))
+	}
+
+	return fmt.Errorf("%s is not a map or an array", path)
+}
+
+// listKeys returns the keys as a comma-separated list, eliding all but the
+// first few
+func listKeys(keys []string) string {
+	if len(keys) == 0 {
+		return "none"
+	}
+
+	if len(keys) > maxListedKeys {
+		return fmt.Sprintf("%s, and %d more", strings.Join(keys[:maxListedKeys], ", "), len(keys)-maxListedKeys)
+	}
+
+	return strings.Join(keys, ", ")
+}
+
+// didYouMean returns a suggestion of the candidates closest to s, or an empty
+// string if none are close enough to be likely
+func didYouMean(s string, candidates []string) string {
+	matches := suggest(s, candidates)
+	if len(matches) == 0 {
+		return ""
+	}
+
+	quoted := make([]string, len(matches))
+	for i, m := range matches {
+		quoted[i] = fmt.Sprintf("%q", m)
+	}
+
+	return fmt.Sprintf("; did you mean %s?", strings.Join(quoted, " or "))
+}
+
+// suggest returns the candidates with the smallest edit distance from s, as
+// long as that distance is small relative to the length of s
+func suggest(s string, candidates []string) []string {
+	n := len([]rune(s))
+	threshold := n / 3
+	if threshold < 2 {
+		threshold = 2
+	}
+
+	var matches []string
+	best := threshold + 1
+
+	for _, c := range candidates {
+		d := levenshtein(s, c)
+		if d == 0 || d >= n {
+			continue
+		}
+
+		switch {
+		case d < best:
+			best = d
+			matches = []string{c}
+		case d == best:
+			matches = append(matches, c)
+		}
+	}
+
+	return matches
+}
+
+// levenshtein returns the number of single-character insertions, deletions
+// and substitutions needed to turn a into b
+func levenshtein(a, b string) int {
+	ar, br := []rune(a), []rune(b)
+
+	prev := make([]int, len(br)+1)
+	cur := make([]int, len(br)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ar); i++ {
+		cur[0] = i
+		for j := 1; j <= len(br); j++ {
+			cost := 1
+			if ar[i-1] == br[j-1] {
+				cost = 0
+			}
+
+			cur[j] = minInt(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+
+	return prev[len(br)]
+}
+
+func minInt(n int, ns ...int) int {
+	for _, m := range ns {
+		if m < n {
+			n = m
+		}
+	}
+	return n
+}
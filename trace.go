@@ -0,0 +1,272 @@
+package yamlpatch
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// Trace records what each operation in a patch did
+type Trace []OpTrace
+
+// OpTrace records what a single operation did
+type OpTrace struct {
+	Index int
+	Op    Op
+	// Path is the path of the operation as written
+	Path OpPath
+	// Paths are the changes made at each path the operation's path expanded
+	// to
+	Paths []PathTrace
+	Err   error
+}
+
+// PathTrace records the change an operation made at a single concrete path
+type PathTrace struct {
+	Path OpPath
+	// Old and New are the values at the path before and after the operation,
+	// or nil if there was no value
+	Old  *Node
+	New  *Node
+	NoOp bool
+	Err  error
+}
+
+// ApplyWithTrace is like ApplyWithOptions, but also returns a trace of what
+// each operation did
+func (p Patch) ApplyWithTrace(doc []byte, opts ApplyOptions) ([]byte, Trace, error) {
+	t := &tracer{}
+	bs, err := p.applyDoc(doc, opts, t)
+	return bs, t.trace, err
+}
+
+// tracer is an observer that builds a Trace
+type tracer struct {
+	trace  Trace
+	before interface{}
+}
+
+func (t *tracer) expanded(index int, op *Operation, err error) {
+	t.trace = append(t.trace, OpTrace{
+		Index: index,
+		Op:    op.Op,
+		Path:  op.Path,
+		Err:   err,
+	})
+}
+
+func (t *tracer) beforeOp(index int, op *Operation, c Container) error {
+	t.before, _ = containerValue(c)
+	t.before = copyValue(t.before)
+
+	cur := &t.trace[len(t.trace)-1]
+	cur.Paths = append(cur.Paths, PathTrace{
+		Path: op.Path,
+		Old:  lookupNode(c, op.Path).clone(),
+	})
+
+	return nil
+}
+
+func (t *tracer) afterOp(index int, op *Operation, c Container, err error) {
+	cur := &t.trace[len(t.trace)-1]
+	pt := &cur.Paths[len(cur.Paths)-1]
+
+	if err != nil {
+		pt.Err = err
+		cur.Err = err
+		return
+	}
+
+	pt.Path, pt.New = performedAt(c, op)
+
+	after, _ := containerValue(c)
+	pt.NoOp = reflect.DeepEqual(t.before, after)
+}
+
+// performedAt returns the concrete path an operation was performed at and a
+// copy of the value left there. A removed path is not looked up again, since
+// the element that followed a removed array element has taken its place.
+func performedAt(c Container, op *Operation) (OpPath, *Node) {
+	if op.Op == opRemove {
+		return op.Path, nil
+	}
+
+	path := concretePath(c, op.Path)
+	return path, lookupNode(c, path).clone()
+}
+
+// lookupNode returns the node at the path, or nil if there is none
+func lookupNode(c Container, path OpPath) *Node {
+	con, key, err := findContainer(c, &path)
+	if err != nil {
+		return nil
+	}
+
+	node, err := con.Get(key)
+	if err != nil {
+		return nil
+	}
+
+	return node
+}
+
+// concretePath replaces a trailing "-" with the index of the last element of
+// the array it refers to
+func concretePath(c Container, path OpPath) OpPath {
+	parts, key, err := path.Decompose()
+	if err != nil || key != "-" {
+		return path
+	}
+
+	if len(parts) == 0 {
+		return path
+	}
+
+	parent := OpPath("/" + strings.Join(parts, "/"))
+	if node := lookupNode(c, parent); node != nil {
+		if ary, ok := node.Container().(*nodeSlice); ok && len(*ary) > 0 {
+			return OpPath(fmt.Sprintf("%s/%d", parent, len(*ary)-1))
+		}
+	}
+
+	return path
+}
+
+// WriteText writes the trace in a human-readable form
+func (t Trace) WriteText(w io.Writer) error {
+	for _, ot := range t {
+		_, err := fmt.Fprintf(w, "operation %d: %s %s\n", ot.Index, ot.Op, ot.Path)
+		if err != nil {
+			return err
+		}
+
+		if len(ot.Paths) == 0 && ot.Err != nil {
+			_, err = fmt.Fprintf(w, "  error: %s\n", ot.Err)
+			if err != nil {
+				return err
+			}
+		}
+
+		for _, pt := range ot.Paths {
+			var line string
+			switch {
+			case pt.Err != nil:
+				line = fmt.Sprintf("  %s: error: %s", pt.Path, pt.Err)
+			case pt.NoOp:
+				line = fmt.Sprintf("  %s: no-op", pt.Path)
+			default:
+				line = fmt.Sprintf("  %s: %s -> %s", pt.Path, textValue(pt.Old), textValue(pt.New))
+			}
+
+			_, err = fmt.Fprintln(w, line)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func textValue(n *Node) string {
+	if n == nil {
+		return "(absent)"
+	}
+
+	bs, err := json.Marshal(jsonValue(n.Value()))
+	if err != nil {
+		return fmt.Sprint(n.Value())
+	}
+
+	return string(bs)
+}
+
+type jsonOpTrace struct {
+	Index int             `json:"index"`
+	Op    Op              `json:"op"`
+	Path  OpPath          `json:"path"`
+	Paths []jsonPathTrace `json:"paths"`
+	Error string          `json:"error,omitempty"`
+}
+
+type jsonPathTrace struct {
+	Path  OpPath       `json:"path"`
+	Old   *interface{} `json:"old,omitempty"`
+	New   *interface{} `json:"new,omitempty"`
+	NoOp  bool         `json:"noop"`
+	Error string       `json:"error,omitempty"`
+}
+
+// WriteJSON writes the trace as JSON lines, one per operation. Old and new
+// values are omitted when there was no value.
+func (t Trace) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+
+	for _, ot := range t {
+		jot := jsonOpTrace{
+			Index: ot.Index,
+			Op:    ot.Op,
+			Path:  ot.Path,
+			Paths: []jsonPathTrace{},
+			Error: errorString(ot.Err),
+		}
+
+		for _, pt := range ot.Paths {
+			jot.Paths = append(jot.Paths, jsonPathTrace{
+				Path:  pt.Path,
+				Old:   jsonNode(pt.Old),
+				New:   jsonNode(pt.New),
+				NoOp:  pt.NoOp,
+				Error: errorString(pt.Err),
+			})
+		}
+
+		err := enc.Encode(jot)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func errorString(err error) string {
+	if err == nil {
+		return ""
+	}
+
+	return err.Error()
+}
+
+func jsonNode(n *Node) *interface{} {
+	if n == nil {
+		return nil
+	}
+
+	v := jsonValue(n.Value())
+	return &v
+}
+
+// jsonValue converts a value unmarshaled from YAML into one that can be
+// marshaled as JSON, which requires maps to have string keys
+func jsonValue(v interface{}) interface{} {
+	switch vt := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(vt))
+		for k, e := range vt {
+			m[fmt.Sprint(k)] = jsonValue(e)
+		}
+		return m
+	case []interface{}:
+		ary := make([]interface{}, len(vt))
+		for i, e := range vt {
+			ary[i] = jsonValue(e)
+		}
+		return ary
+	}
+
+	return v
+}
@@ -0,0 +1,119 @@
+package yamlpatch_test
+
+import (
+	"bytes"
+	"errors"
+
+	yamlpatch "github.com/krishicks/yaml-patch"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Trace", func() {
+	var (
+		trace yamlpatch.Trace
+		err   error
+	)
+
+	BeforeEach(func() {
+		patch, decodeErr := yamlpatch.DecodePatch([]byte(`---
+- op: add
+  path: /jobs/get=A/trigger
+  value: true
+- op: add-unique
+  path: /tags
+  value: a
+- op: add
+  path: /tags/-
+  value: b
+- op: remove
+  path: /jobs/name=job3
+`))
+		Expect(decodeErr).NotTo(HaveOccurred())
+
+		_, trace, err = patch.ApplyWithTrace([]byte(`---
+tags: [a]
+jobs:
+- name: job1
+  plan:
+  - get: A
+- name: job2
+  plan:
+  - get: A
+    trigger: false
+`), yamlpatch.ApplyOptions{})
+	})
+
+	It("records the concrete paths and values of each operation", func() {
+		Expect(err).To(HaveOccurred())
+		Expect(trace).To(HaveLen(4))
+
+		Expect(trace[0].Index).To(Equal(0))
+		Expect(trace[0].Path).To(Equal(yamlpatch.OpPath("/jobs/get=A/trigger")))
+		Expect(trace[0].Paths).To(HaveLen(2))
+		Expect(trace[0].Paths[0].Path).To(Equal(yamlpatch.OpPath("/jobs/0/plan/0/trigger")))
+		Expect(trace[0].Paths[0].Old).To(BeNil())
+		Expect(trace[0].Paths[0].New.Value()).To(Equal(true))
+		Expect(trace[0].Paths[1].Path).To(Equal(yamlpatch.OpPath("/jobs/1/plan/0/trigger")))
+		Expect(trace[0].Paths[1].Old.Value()).To(Equal(false))
+		Expect(trace[0].Paths[1].New.Value()).To(Equal(true))
+
+		Expect(trace[1].Paths).To(HaveLen(1))
+		Expect(trace[1].Paths[0].NoOp).To(BeTrue())
+
+		Expect(trace[2].Paths[0].Path).To(Equal(yamlpatch.OpPath("/tags/1")))
+		Expect(trace[2].Paths[0].NoOp).To(BeFalse())
+
+		Expect(trace[3].Paths).To(BeEmpty())
+		Expect(trace[3].Err).To(MatchError(`could not expand pointer: /jobs/name=job3: nothing under /jobs has name=job3 (values of name: job1, job2); did you mean "name=job1" or "name=job2"?`))
+	})
+
+	It("writes the trace as text", func() {
+		var buf bytes.Buffer
+		Expect(trace.WriteText(&buf)).To(Succeed())
+		Expect(buf.String()).To(Equal(`operation 0: add /jobs/get=A/trigger
+  /jobs/0/plan/0/trigger: (absent) -> true
+  /jobs/1/plan/0/trigger: false -> true
+operation 1: add-unique /tags
+  /tags: no-op
+operation 2: add /tags/-
+  /tags/1: (absent) -> "b"
+operation 3: remove /jobs/name=job3
+  error: could not expand pointer: /jobs/name=job3: nothing under /jobs has name=job3 (values of name: job1, job2); did you mean "name=job1" or "name=job2"?
+`))
+	})
+
+	It("writes the trace as JSON lines", func() {
+		var buf bytes.Buffer
+		Expect(trace.WriteJSON(&buf)).To(Succeed())
+		Expect(buf.String()).To(Equal(`{"index":0,"op":"add","path":"/jobs/get=A/trigger","paths":[{"path":"/jobs/0/plan/0/trigger","new":true,"noop":false},{"path":"/jobs/1/plan/0/trigger","old":false,"new":true,"noop":false}]}
+{"index":1,"op":"add-unique","path":"/tags","paths":[{"path":"/tags","old":["a"],"new":["a"],"noop":true}]}
+{"index":2,"op":"add","path":"/tags/-","paths":[{"path":"/tags/1","new":"b","noop":false}]}
+{"index":3,"op":"remove","path":"/jobs/name=job3","paths":[],"error":"could not expand pointer: /jobs/name=job3: nothing under /jobs has name=job3 (values of name: job1, job2); did you mean \"name=job1\" or \"name=job2\"?"}
+`))
+	})
+
+	It("records a removed array element as absent", func() {
+		patch, err := yamlpatch.DecodePatch([]byte(`---
+- op: remove
+  path: /list/0
+`))
+		Expect(err).NotTo(HaveOccurred())
+
+		_, trace, err := patch.ApplyWithTrace([]byte("list: [a, b]\n"), yamlpatch.ApplyOptions{})
+		Expect(err).NotTo(HaveOccurred())
+
+		var buf bytes.Buffer
+		Expect(trace.WriteText(&buf)).To(Succeed())
+		Expect(buf.String()).To(Equal("operation 0: remove /list/0\n  /list/0: \"a\" -> (absent)\n"))
+	})
+
+	It("records failed operations", func() {
+		trace := yamlpatch.Trace{{Index: 0, Op: "remove", Path: "/foo", Paths: []yamlpatch.PathTrace{{Path: "/foo", Err: errors.New("boom")}}, Err: errors.New("boom")}}
+
+		var buf bytes.Buffer
+		Expect(trace.WriteText(&buf)).To(Succeed())
+		Expect(buf.String()).To(Equal("operation 0: remove /foo\n  /foo: error: boom\n"))
+	})
+})
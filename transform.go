@@ -0,0 +1,61 @@
+package yamlpatch
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+// Transform functions
+const (
+	transformReplace     = "replace"
+	transformAddPrefix   = "add-prefix"
+	transformAddSuffix   = "add-suffix"
+	transformStripPrefix = "strip-prefix"
+	transformStripSuffix = "strip-suffix"
+	transformUpper       = "upper"
+	transformLower       = "lower"
+	transformFormat      = "format"
+)
+
+// transformString applies the named function to s. The argument is the
+// replacement for "replace", the prefix or suffix for the prefix and suffix
+// functions, and a text/template with s as its data for "format".
+func transformString(function, pattern, arg, s string) (string, error) {
+	switch function {
+	case transformReplace:
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return "", err
+		}
+		return re.ReplaceAllString(s, arg), nil
+	case transformAddPrefix:
+		return arg + s, nil
+	case transformAddSuffix:
+		return s + arg, nil
+	case transformStripPrefix:
+		return strings.TrimPrefix(s, arg), nil
+	case transformStripSuffix:
+		return strings.TrimSuffix(s, arg), nil
+	case transformUpper:
+		return strings.ToUpper(s), nil
+	case transformLower:
+		return strings.ToLower(s), nil
+	case transformFormat:
+		tmpl, err := template.New("format").Option("missingkey=error").Parse(arg)
+		if err != nil {
+			return "", err
+		}
+
+		var buf bytes.Buffer
+		err = tmpl.Execute(&buf, s)
+		if err != nil {
+			return "", err
+		}
+		return buf.String(), nil
+	}
+
+	return "", fmt.Errorf("Unexpected transform function: %s", function)
+}
@@ -0,0 +1,165 @@
+package yamlpatch
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// refKey is the key of a map that is replaced by the value at the pointer it
+// holds, e.g. { $ref: /defaults/timeout }
+const refKey = "$ref"
+
+// resolveValue returns the operation with a literal Value, taken from the
+// document when ValueFrom is set, or with any references embedded in Value
+// replaced by the values they point at when ResolveRefs is set. Otherwise the
+// operation is returned with a copy of its Value, so that the document never
+// shares nodes with the patch and the patch can be applied again.
+func (o *Operation) resolveValue(c Container) (*Operation, error) {
+	if o.ValueFile != "" || o.ValueEnv != "" {
+		return nil, errors.New("valueFile and valueEnv must be loaded by DecodePatchFile")
+	}
+
+	op := *o
+
+	if o.ValueFrom == "" && !o.ResolveRefs {
+		op.Value = o.Value.clone()
+		return &op, nil
+	}
+
+	if o.ValueFrom != "" {
+		if o.Value != nil {
+			return nil, errors.New("value and valueFrom cannot both be set")
+		}
+
+		node, err := findNode(c, o.ValueFrom)
+		if err != nil {
+			return nil, fmt.Errorf("could not resolve valueFrom: %s", err)
+		}
+
+		val := copyValue(node.Value())
+		op.Value = NewNode(&val)
+		op.ValueFrom = ""
+		return &op, nil
+	}
+
+	if o.Value == nil {
+		return nil, errors.New("missing value")
+	}
+
+	val, err := resolveRefs(c, o.Value.Value())
+	if err != nil {
+		return nil, err
+	}
+
+	op.Value = NewNode(&val)
+	op.ResolveRefs = false
+	return &op, nil
+}
+
+// expandPointer returns the single canonical path matched by the pointer,
+// which may use extended syntax
+func expandPointer(c Container, path OpPath) (OpPath, error) {
+	if !path.ContainsExtendedSyntax() {
+		return path, nil
+	}
+
+	paths := NewPathFinder(c).Find(string(path))
+	switch len(paths) {
+	case 0:
+		return "", fmt.Errorf("could not expand pointer: %s: %s", path, NewPathFinder(c).explain(string(path)))
+	case 1:
+		return OpPath(paths[0]), nil
+	}
+
+	return "", fmt.Errorf("pointer %s is ambiguous, it matches %d paths: %s", path, len(paths), strings.Join(paths, ", "))
+}
+
+// findNode returns the single node at the given pointer, which may use
+// extended syntax
+func findNode(c Container, path OpPath) (*Node, error) {
+	path, err := expandPointer(c, path)
+	if err != nil {
+		return nil, err
+	}
+
+	con, key, err := findContainer(c, &path)
+	if err != nil {
+		return nil, fmt.Errorf("path does not exist: %s: %s", path, err)
+	}
+
+	node, err := con.Get(key)
+	if err != nil || node == nil {
+		return nil, fmt.Errorf("path does not exist: %s: %s", path, missingKey(con, parentPath(path), key))
+	}
+
+	return node, nil
+}
+
+func refPointer(v interface{}) (OpPath, bool) {
+	m, ok := v.(map[interface{}]interface{})
+	if !ok || len(m) != 1 {
+		return "", false
+	}
+
+	ref, ok := m[refKey].(string)
+	return OpPath(ref), ok
+}
+
+// resolveRefs returns a copy of the value with every reference replaced by a
+// copy of the value it points at
+func resolveRefs(c Container, v interface{}) (interface{}, error) {
+	if ref, ok := refPointer(v); ok {
+		node, err := findNode(c, ref)
+		if err != nil {
+			return nil, fmt.Errorf("could not resolve %s: %s", refKey, err)
+		}
+
+		return copyValue(node.Value()), nil
+	}
+
+	switch vt := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[interface{}]interface{}, len(vt))
+		for k, e := range vt {
+			r, err := resolveRefs(c, e)
+			if err != nil {
+				return nil, err
+			}
+			m[k] = r
+		}
+		return m, nil
+	case []interface{}:
+		ary := make([]interface{}, len(vt))
+		for i, e := range vt {
+			r, err := resolveRefs(c, e)
+			if err != nil {
+				return nil, err
+			}
+			ary[i] = r
+		}
+		return ary, nil
+	}
+
+	return v, nil
+}
+
+// copyValue returns a deep copy of a value unmarshaled from YAML
+func copyValue(v interface{}) interface{} {
+	switch vt := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[interface{}]interface{}, len(vt))
+		for k, e := range vt {
+			m[k] = copyValue(e)
+		}
+		return m
+	case []interface{}:
+		ary := make([]interface{}, len(vt))
+		for i, e := range vt {
+			ary[i] = copyValue(e)
+		}
+		return ary
+	}
+
+	return v
+}
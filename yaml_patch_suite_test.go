@@ -1,6 +1,8 @@
 package yamlpatch_test
 
 import (
+	yamlpatch "github.com/krishicks/yaml-patch"
+
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
@@ -11,3 +13,10 @@ func TestYamlPatch(t *testing.T) {
 	RegisterFailHandler(Fail)
 	RunSpecs(t, "YamlPatch Suite")
 }
+
+// decodePatch decodes an ops file, failing the spec if it is invalid
+func decodePatch(ops string) yamlpatch.Patch {
+	patch, err := yamlpatch.DecodePatch([]byte(ops))
+	Expect(err).NotTo(HaveOccurred())
+	return patch
+}